@@ -0,0 +1,192 @@
+package icinga2
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ActionOptions controls execution of filter-based actions
+type ActionOptions struct {
+	// only return objects matched by filter without executing the action
+	DryRun bool
+	// abort if filter matches more objects, 0 means no limit
+	MaxObjects int
+}
+
+// TooManyObjectsError is returned when filter matches more objects than ActionOptions.MaxObjects allows
+type TooManyObjectsError struct {
+	Filter  string
+	Matched int
+	Limit   int
+}
+
+func (e *TooManyObjectsError) Error() string {
+	return fmt.Sprintf("filter [%s] matches %d objects, limit is %d", e.Filter, e.Matched, e.Limit)
+}
+
+// check returns error if number of matched objects exceeds the limit
+func (o ActionOptions) check(filter string, matched int) error {
+	if o.MaxObjects > 0 && matched > o.MaxObjects {
+		return &TooManyObjectsError{Filter: filter, Matched: matched, Limit: o.MaxObjects}
+	}
+	return nil
+}
+
+// needsPreview returns true if objects have to be queried before running action
+func (o ActionOptions) needsPreview() bool {
+	return o.DryRun || o.MaxObjects > 0
+}
+
+// MatchObjects returns sorted names of objects of type (e.g. "Host", "Service") matching filter.
+// Filter matching no objects is not an error
+func (a *API) MatchObjects(objType string, filter string) (names []string, err error) {
+	i, err := a.QueryObjects(pluralize(objType), QueryOptions{Filter: filter, Attrs: []string{"name"}})
+	if apiErr, ok := err.(*APIError); ok && apiErr.Code == http.StatusNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		return names, err
+	}
+	names = make([]string, 0, len(i.Results))
+	for _, obj := range i.Results {
+		names = append(names, obj.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// guardAction previews objects matched by filter if options require it.
+// Returns false if action should not run, with matched objects for dry run
+func (a *API) guardAction(objType string, filter string, opts ActionOptions) (matched []string, run bool, err error) {
+	if !opts.needsPreview() {
+		return nil, true, nil
+	}
+	matched, err = a.MatchObjects(objType, filter)
+	if err != nil {
+		return matched, false, fmt.Errorf("error previewing objects: %s", err)
+	}
+	err = opts.check(filter, len(matched))
+	if err != nil {
+		return matched, false, err
+	}
+	return matched, !opts.DryRun, nil
+}
+
+// MatchObjects returns names of objects matching filter on each server.
+// Unlike queries it returns error if any server failed, as object limit can't be checked without all of them
+func (a *Proxy) MatchObjects(objType string, filter string) (map[string][]string, error) {
+	var lock sync.Mutex
+	res := make(map[string][]string)
+	errs := a.each(func(name string, s *API) error {
+		names, err := s.MatchObjects(objType, filter)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		res[name] = names
+		return nil
+	})
+	return res, anyServerError(errs)
+}
+
+// anyServerError returns error describing all failed servers, nil if none failed
+func anyServerError(errs map[string]error) error {
+	failed := make([]string, 0)
+	for name, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("failed on %d of %d servers: %s", len(failed), len(errs), strings.Join(failed, "; "))
+}
+
+// CheckObjectLimit previews objects of type (e.g. "Host") matched by filter on all servers and returns *TooManyObjectsError
+// if together they are more than limit, for actions Proxy doesn't run itself. Failure of any server is an error too
+func (a *Proxy) CheckObjectLimit(objType string, filter string, limit int) error {
+	_, _, err := a.guardAction(objType, filter, ActionOptions{MaxObjects: limit})
+	return err
+}
+
+// guardAction previews objects matched by filter on all servers, limit applies to the total number of objects.
+// Returns false if action should not run, with deduplicated matched objects for dry run
+func (a *Proxy) guardAction(objType string, filter string, opts ActionOptions) (matched []string, run bool, err error) {
+	if !opts.needsPreview() {
+		return nil, true, nil
+	}
+	perServer, err := a.MatchObjects(objType, filter)
+	if err != nil {
+		return matched, false, fmt.Errorf("error previewing objects: %s", err)
+	}
+	total := 0
+	unique := make(map[string]bool)
+	for _, names := range perServer {
+		total += len(names)
+		for _, name := range names {
+			unique[name] = true
+		}
+	}
+	matched = make([]string, 0, len(unique))
+	for name := range unique {
+		matched = append(matched, name)
+	}
+	sort.Strings(matched)
+	err = opts.check(filter, total)
+	if err != nil {
+		return matched, false, err
+	}
+	return matched, !opts.DryRun, nil
+}
+
+// filterAction executes action on objects of type matched by filter, params are added to the request
+func (a *API) filterAction(action string, objType string, filter string, params map[string]interface{}, opts ActionOptions) (ActionResults, error) {
+	if filter == "" {
+		return nil, fmt.Errorf("action %s needs filter", action)
+	}
+	matched, run, err := a.guardAction(objType, filter, opts)
+	if !run {
+		return dryRunResults(matched), err
+	}
+	req := map[string]interface{}{"type": objType, "filter": filter}
+	for k, v := range params {
+		req[k] = v
+	}
+	return a.postAction(action, req)
+}
+
+// proxyAction guards action on all servers then runs it on each of them, merging results ordered by server name
+func (a *Proxy) proxyAction(objType string, filter string, opts ActionOptions, f func(s *API) (ActionResults, error)) (ActionResults, error) {
+	matched, run, err := a.guardAction(objType, filter, opts)
+	if !run {
+		return dryRunResults(matched), err
+	}
+	var lock sync.Mutex
+	res := make(map[string]ActionResults)
+	errs := a.each(func(name string, s *API) error {
+		results, err := f(s)
+		lock.Lock()
+		defer lock.Unlock()
+		res[name] = results
+		return err
+	})
+	servers := make([]string, 0, len(res))
+	for k := range res {
+		servers = append(servers, k)
+	}
+	sort.Strings(servers)
+	results := make(ActionResults, 0)
+	for _, k := range servers {
+		for _, r := range res[k] {
+			r.Server = k
+			results = append(results, r)
+		}
+	}
+	return results, proxyError(errs)
+}
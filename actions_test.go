@@ -0,0 +1,162 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var downtimeFiles = map[string]string{
+	"/v1/objects/Hosts":             "v1.objects.hosts.json",
+	"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json",
+}
+
+func testDowntime() Downtime {
+	return Downtime{
+		Start:   time.Now(),
+		End:     time.Now().Add(time.Hour),
+		Author:  "ops",
+		Comment: "maintenance",
+	}
+}
+
+func TestAPI_MatchObjects(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	names, err := Api.MatchObjects("Host", `match("t1-*", host.name)`)
+	require.Nil(t, err)
+	assert.Len(t, names, 7)
+	require.Len(t, ts.Requests(), 1)
+	q := ts.Requests()[0].Query()
+	assert.Equal(t, []string{"name"}, q["attrs"])
+	assert.Equal(t, `match("t1-*", host.name)`, q.Get("filter"))
+}
+
+func TestAPI_ScheduleHostDowntime_DryRun(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	hosts, err := Api.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{DryRun: true})
+	require.Nil(t, err)
+	assert.Len(t, hosts, 7)
+	require.Len(t, ts.Requests(), 1, "only preview query should be sent")
+	assert.Equal(t, "GET", ts.Requests()[0].Method)
+
+	_, err = Api.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, Downtime{}, ActionOptions{DryRun: true})
+	assert.NotNil(t, err, "downtime is validated in dry run")
+}
+
+func TestAPI_ScheduleHostDowntime_Limit(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{MaxObjects: 5})
+	require.NotNil(t, err)
+	limitErr, ok := err.(*TooManyObjectsError)
+	require.True(t, ok, "should be *TooManyObjectsError")
+	assert.Equal(t, 7, limitErr.Matched)
+	assert.Equal(t, `filter [match("t1-*", host.name)] matches 7 objects, limit is 5`, err.Error())
+	assert.Len(t, ts.Requests(), 1, "action should not be sent")
+
+	ts.Reset()
+	_, err = Api.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{MaxObjects: 7})
+	require.Nil(t, err)
+	require.Len(t, ts.Requests(), 2)
+	assert.Equal(t, "POST", ts.Requests()[1].Method)
+	assert.Equal(t, "/v1/actions/schedule-downtime", ts.Requests()[1].URI)
+}
+
+func TestProxy_ScheduleHostDowntime_Options(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	ts2 := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	defer ts2.Close()
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	hosts, err := p.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{DryRun: true})
+	require.Nil(t, err)
+	assert.Len(t, hosts, 7, "deduplicated")
+	_, err = p.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{MaxObjects: 10})
+	assert.IsType(t, &TooManyObjectsError{}, err, "limit applies to sum of all servers")
+	assert.Len(t, ts.Requests(), 2)
+	assert.Len(t, ts2.Requests(), 2)
+}
+
+func TestProxy_ScheduleHostDowntime_PreviewFailed(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	ts2 := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/objects/Hosts":             "500 error.internal.json",
+		"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json",
+	})
+	defer ts.Close()
+	defer ts2.Close()
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	_, err = p.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{MaxObjects: 10})
+	assert.EqualError(t, err, "error previewing objects: failed on 1 of 2 servers: s2: [500] Error: Evaluation failed")
+	_, err = p.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{DryRun: true})
+	assert.Error(t, err, "dry run can't list objects of failed server")
+	for _, r := range append(ts.Requests(), ts2.Requests()...) {
+		assert.Equal(t, "GET", r.Method, "action should not be sent")
+	}
+
+	// server without matching objects is not a failure
+	ts3 := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/objects/Hosts":             "404 error.no-objects-found.json",
+		"/v1/actions/schedule-downtime": "404 error.no-objects-found.json",
+	})
+	defer ts3.Close()
+	p, err = NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s3": {ServerURL: ts3.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	results, err := p.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{MaxObjects: 10})
+	require.Nil(t, err)
+	assert.NotEmpty(t, results.Succeeded())
+}
+
+func TestAPI_ScheduleHostDowntime_PreviewFailed(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "500 error.internal.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.ScheduleHostDowntimeByFilterWithOptions(`match("t1-*", host.name)`, testDowntime(), ActionOptions{MaxObjects: 10})
+	assert.EqualError(t, err, "error previewing objects: [500] Error: Evaluation failed")
+	assert.Len(t, ts.Requests(), 1, "action should not be sent")
+}
+
+func TestScheduleHostDowntime_EmptyFilter(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.ScheduleHostDowntimeByFilterWithOptions("", testDowntime(), ActionOptions{})
+	assert.EqualError(t, err, "action schedule-downtime needs filter")
+	_, err = Api.scheduleDowntime("Service", "", testDowntime())
+	assert.EqualError(t, err, "action schedule-downtime needs filter")
+	p, err := NewProxy(map[string]Icinga2ServerConfig{"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass}})
+	require.Nil(t, err)
+	_, err = p.ScheduleHostDowntimeByFilterWithOptions("", testDowntime(), ActionOptions{MaxObjects: 10})
+	assert.EqualError(t, err, "action schedule-downtime needs filter")
+	assert.Empty(t, ts.Requests(), "nothing should be sent")
+}
@@ -0,0 +1,210 @@
+package icinga2
+
+import (
+	"fmt"
+	"github.com/efigence/go-monitoring"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedProxy keeps in-memory copy of hosts and services from all Proxy servers and refreshes it in background.
+// If server fails to respond its last good snapshot is served until it comes back.
+type CachedProxy struct {
+	proxy    *Proxy
+	interval time.Duration
+	lock     sync.RWMutex
+	servers  map[string]*cacheEntry
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type cacheEntry struct {
+	hosts           []monitoring.Host
+	services        []monitoring.Service
+	hostsUpdated    time.Time
+	servicesUpdated time.Time
+	lastError       error
+}
+
+// CacheStatus describes freshness of data cached from single Icinga2 server
+type CacheStatus struct {
+	HostsUpdated    time.Time
+	ServicesUpdated time.Time
+	// error from last refresh attempt, nil if it was successful
+	LastError error
+}
+
+// NeverUpdated is Age of cached data that was never fetched successfully
+const NeverUpdated = time.Duration(math.MaxInt64)
+
+// Age returns age of the oldest part of cached data, NeverUpdated if hosts or services were never fetched
+func (c CacheStatus) Age() time.Duration {
+	if c.HostsUpdated.IsZero() || c.ServicesUpdated.IsZero() {
+		return NeverUpdated
+	}
+	oldest := c.HostsUpdated
+	if c.ServicesUpdated.Before(oldest) {
+		oldest = c.ServicesUpdated
+	}
+	return time.Since(oldest)
+}
+
+// NewCachedProxy does initial synchronous refresh then starts refreshing data from all proxy servers every interval.
+func NewCachedProxy(p *Proxy, interval time.Duration) *CachedProxy {
+	c := &CachedProxy{
+		proxy:    p,
+		interval: interval,
+		servers:  make(map[string]*cacheEntry),
+		stop:     make(chan struct{}),
+	}
+	c.Refresh()
+	go c.run()
+	return c
+}
+
+func (c *CachedProxy) run() {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+			c.Refresh()
+		}
+	}
+}
+
+// Close stops background refresh. Cached data is still available after close
+func (c *CachedProxy) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// Refresh fetches hosts and services from all servers, replacing snapshot of each server that answered successfully
+func (c *CachedProxy) Refresh() {
+	hosts, hostErrs := c.proxy.fetchHosts("")
+	services, serviceErrs := c.proxy.fetchServices("")
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// fetch sets error entry (even if nil) for every server it queried
+	for k := range c.servers {
+		if _, ok := hostErrs[k]; !ok {
+			delete(c.servers, k)
+		}
+	}
+	for k := range hostErrs {
+		e, ok := c.servers[k]
+		if !ok {
+			e = &cacheEntry{}
+			c.servers[k] = e
+		}
+		var errs []string
+		if hostErrs[k] == nil {
+			e.hosts = hosts[k]
+			e.hostsUpdated = now
+		} else {
+			log.Printf("error refreshing hosts from %s: %s", k, hostErrs[k])
+			errs = append(errs, fmt.Sprintf("hosts: %s", hostErrs[k]))
+		}
+		switch err, ok := serviceErrs[k]; {
+		case !ok:
+			// server added by reload between fetches
+		case err == nil:
+			e.services = services[k]
+			e.servicesUpdated = now
+		default:
+			log.Printf("error refreshing services from %s: %s", k, err)
+			errs = append(errs, fmt.Sprintf("services: %s", err))
+		}
+		e.lastError = nil
+		if len(errs) > 0 {
+			e.lastError = fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+	}
+}
+
+// Status returns freshness of cached data per server
+func (c *CachedProxy) Status() map[string]CacheStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	out := make(map[string]CacheStatus, len(c.servers))
+	for k, e := range c.servers {
+		out[k] = CacheStatus{
+			HostsUpdated:    e.hostsUpdated,
+			ServicesUpdated: e.servicesUpdated,
+			LastError:       e.lastError,
+		}
+	}
+	return out
+}
+
+func (c *CachedProxy) GetHosts() (m []monitoring.Host, err error) {
+	return c.GetHostsFunc(nil)
+}
+
+// GetHostsByFilter returns cached data for empty filter, anything else is passed to the Icinga2 servers
+func (c *CachedProxy) GetHostsByFilter(filter string) (m []monitoring.Host, err error) {
+	if filter == "" {
+		return c.GetHosts()
+	}
+	return c.proxy.GetHostsByFilter(filter)
+}
+
+// GetHostsFunc returns cached hosts for which f returns true. nil f returns all hosts
+func (c *CachedProxy) GetHostsFunc(f func(monitoring.Host) bool) (m []monitoring.Host, err error) {
+	c.lock.RLock()
+	res := make(map[string][]monitoring.Host, len(c.servers))
+	for k, e := range c.servers {
+		if !e.hostsUpdated.IsZero() {
+			res[k] = e.hosts
+		}
+	}
+	c.lock.RUnlock()
+	if len(res) == 0 {
+		return m, fmt.Errorf("no cached host data: %+v", c.Status())
+	}
+	m = make([]monitoring.Host, 0)
+	for _, h := range c.proxy.mergeHosts(res) {
+		if f == nil || f(h) {
+			m = append(m, h)
+		}
+	}
+	return m, nil
+}
+
+func (c *CachedProxy) GetServices() (m []monitoring.Service, err error) {
+	return c.GetServicesFunc(nil)
+}
+
+// GetServicesByFilter returns cached data for empty filter, anything else is passed to the Icinga2 servers
+func (c *CachedProxy) GetServicesByFilter(filter string) (m []monitoring.Service, err error) {
+	if filter == "" {
+		return c.GetServices()
+	}
+	return c.proxy.GetServicesByFilter(filter)
+}
+
+// GetServicesFunc returns cached services for which f returns true. nil f returns all services
+func (c *CachedProxy) GetServicesFunc(f func(monitoring.Service) bool) (m []monitoring.Service, err error) {
+	c.lock.RLock()
+	res := make(map[string][]monitoring.Service, len(c.servers))
+	for k, e := range c.servers {
+		if !e.servicesUpdated.IsZero() {
+			res[k] = e.services
+		}
+	}
+	c.lock.RUnlock()
+	if len(res) == 0 {
+		return m, fmt.Errorf("no cached service data: %+v", c.Status())
+	}
+	m = make([]monitoring.Service, 0)
+	for _, s := range c.proxy.mergeServices(res) {
+		if f == nil || f(s) {
+			m = append(m, s)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,94 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCachedProxy(t *testing.T) {
+	log = testLogger{}
+	objects := map[string]string{
+		"/v1/objects/Hosts":    "v1.objects.hosts.json",
+		"/v1/objects/Services": "v1.objects.services.json",
+	}
+	ts := icingatest.NewServer(t, "testdata", objects)
+	ts2 := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/objects/Hosts":    "v1.objects.hosts_dedup.json",
+		"/v1/objects/Services": "v1.objects.services_dedup.json",
+	})
+	defer ts.Close()
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	c := NewCachedProxy(p, time.Hour)
+	defer c.Close()
+	t.Run("hosts", func(t *testing.T) {
+		hosts, err := c.GetHosts()
+		assert.Nil(t, err)
+		assert.Len(t, hosts, 14)
+	})
+	t.Run("services", func(t *testing.T) {
+		services, err := c.GetServices()
+		assert.Nil(t, err)
+		assert.Len(t, services, 14)
+	})
+	t.Run("filter func", func(t *testing.T) {
+		hosts, err := c.GetHostsFunc(func(h monitoring.Host) bool { return h.Host == "t1-host1_s1" })
+		assert.Nil(t, err)
+		assert.Len(t, hosts, 1)
+		services, err := c.GetServicesFunc(func(s monitoring.Service) bool { return s.Service == "ELASTICSEARCH" })
+		assert.Nil(t, err)
+		assert.Len(t, services, 2)
+	})
+	t.Run("status", func(t *testing.T) {
+		st := c.Status()
+		assert.Len(t, st, 2)
+		assert.Nil(t, st["s1"].LastError)
+		assert.False(t, st["s1"].HostsUpdated.IsZero())
+		assert.True(t, st["s1"].Age() < time.Minute)
+	})
+	t.Run("stale on error", func(t *testing.T) {
+		ts2.Close()
+		c.Refresh()
+		hosts, err := c.GetHosts()
+		assert.Nil(t, err)
+		assert.Len(t, hosts, 14, "should serve last good snapshot")
+		st := c.Status()
+		if assert.Error(t, st["s2"].LastError) {
+			assert.Contains(t, st["s2"].LastError.Error(), "hosts: ")
+			assert.Contains(t, st["s2"].LastError.Error(), "services: ")
+		}
+		assert.Nil(t, st["s1"].LastError)
+	})
+}
+
+func TestCachedProxy_NoData(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{})
+	ts.Close()
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	c := NewCachedProxy(p, time.Hour)
+	defer c.Close()
+	_, err = c.GetHosts()
+	assert.Error(t, err)
+	_, err = c.GetServices()
+	assert.Error(t, err)
+	assert.Equal(t, NeverUpdated, c.Status()["s1"].Age())
+}
+
+func TestCacheStatus_Age(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, NeverUpdated, CacheStatus{}.Age())
+	assert.Equal(t, NeverUpdated, CacheStatus{HostsUpdated: now}.Age())
+	age := CacheStatus{HostsUpdated: now, ServicesUpdated: now.Add(-time.Hour)}.Age()
+	assert.True(t, age >= time.Hour && age < time.Hour+time.Minute, "age of older part: %s", age)
+}
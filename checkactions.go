@@ -0,0 +1,147 @@
+package icinga2
+
+import (
+	"fmt"
+	"time"
+)
+
+// Acknowledgement of host or service problem
+type Acknowledgement struct {
+	Author  string
+	Comment string
+	// acknowledgement is removed at that time, never if zero
+	Expiry time.Time
+	// keep acknowledgement until object recovers, even if it changes to another problem state
+	Sticky bool
+	// send notification about acknowledgement
+	Notify bool
+	// keep comment after acknowledgement is removed
+	Persistent bool
+}
+
+func (a *Acknowledgement) Validate() error {
+	if a.Author == "" || a.Comment == "" {
+		return fmt.Errorf("acknowledgement needs author and comment")
+	}
+	if !a.Expiry.IsZero() && a.Expiry.Before(time.Now()) {
+		return fmt.Errorf("acknowledgement expiry is in the past")
+	}
+	return nil
+}
+
+func (a *Acknowledgement) params() map[string]interface{} {
+	p := map[string]interface{}{
+		"author":     a.Author,
+		"comment":    a.Comment,
+		"sticky":     a.Sticky,
+		"notify":     a.Notify,
+		"persistent": a.Persistent,
+	}
+	if !a.Expiry.IsZero() {
+		p["expiry"] = a.Expiry.Unix()
+	}
+	return p
+}
+
+func validateObjType(objType string) error {
+	if objType != "Host" && objType != "Service" {
+		return fmt.Errorf("object type has to be Host or Service, not [%s]", objType)
+	}
+	return nil
+}
+
+func validateComment(author string, comment string) error {
+	if author == "" || comment == "" {
+		return fmt.Errorf("comment needs author and text")
+	}
+	return nil
+}
+
+// AcknowledgeProblem acknowledges problems of hosts or services (objType) matched by filter
+func (a *API) AcknowledgeProblem(objType string, filter string, ack Acknowledgement, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	if err := ack.Validate(); err != nil {
+		return nil, err
+	}
+	return a.filterAction("acknowledge-problem", objType, filter, ack.params(), opts)
+}
+
+// RemoveAcknowledgement removes acknowledgements of hosts or services matched by filter
+func (a *API) RemoveAcknowledgement(objType string, filter string, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	return a.filterAction("remove-acknowledgement", objType, filter, nil, opts)
+}
+
+// AddComment adds comment to hosts or services matched by filter, comment names are in ActionResult.Name
+func (a *API) AddComment(objType string, filter string, author string, comment string, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	if err := validateComment(author, comment); err != nil {
+		return nil, err
+	}
+	return a.filterAction("add-comment", objType, filter, map[string]interface{}{"author": author, "comment": comment}, opts)
+}
+
+// RescheduleCheck moves next check of hosts or services matched by filter to nextCheck (now if zero).
+// Force runs check even if active checks are disabled or it is outside of check period
+func (a *API) RescheduleCheck(objType string, filter string, nextCheck time.Time, force bool, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{"force": force}
+	if !nextCheck.IsZero() {
+		params["next_check"] = nextCheck.Unix()
+	}
+	return a.filterAction("reschedule-check", objType, filter, params, opts)
+}
+
+// AcknowledgeProblem acknowledges problems on all servers, object limit applies to the total number of matched objects
+func (a *Proxy) AcknowledgeProblem(objType string, filter string, ack Acknowledgement, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	if err := ack.Validate(); err != nil {
+		return nil, err
+	}
+	return a.proxyAction(objType, filter, opts, func(s *API) (ActionResults, error) {
+		return s.AcknowledgeProblem(objType, filter, ack, ActionOptions{})
+	})
+}
+
+// RemoveAcknowledgement removes acknowledgements on all servers
+func (a *Proxy) RemoveAcknowledgement(objType string, filter string, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	return a.proxyAction(objType, filter, opts, func(s *API) (ActionResults, error) {
+		return s.RemoveAcknowledgement(objType, filter, ActionOptions{})
+	})
+}
+
+// AddComment adds comment to objects on all servers
+func (a *Proxy) AddComment(objType string, filter string, author string, comment string, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	if err := validateComment(author, comment); err != nil {
+		return nil, err
+	}
+	return a.proxyAction(objType, filter, opts, func(s *API) (ActionResults, error) {
+		return s.AddComment(objType, filter, author, comment, ActionOptions{})
+	})
+}
+
+// RescheduleCheck reschedules checks on all servers
+func (a *Proxy) RescheduleCheck(objType string, filter string, nextCheck time.Time, force bool, opts ActionOptions) (ActionResults, error) {
+	if err := validateObjType(objType); err != nil {
+		return nil, err
+	}
+	return a.proxyAction(objType, filter, opts, func(s *API) (ActionResults, error) {
+		return s.RescheduleCheck(objType, filter, nextCheck, force, ActionOptions{})
+	})
+}
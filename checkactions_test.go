@@ -0,0 +1,96 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAPI_AcknowledgeProblem(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/actions/acknowledge-problem": "v1.actions.acknowledge-problem.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	expiry := time.Now().Add(time.Hour)
+	results, err := Api.AcknowledgeProblem("Service", `host.name == "t1-web1"`, Acknowledgement{
+		Author:  "ops",
+		Comment: "looking into it",
+		Expiry:  expiry,
+		Sticky:  true,
+	}, ActionOptions{})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, []string{"t1-web1!HTTP"}, results.Succeeded().Objects())
+	assert.Equal(t, "t1-web1!DISK", results.Failed()[0].Object)
+
+	require.Len(t, ts.Requests(), 1)
+	var req map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(ts.Requests()[0].Body), &req))
+	assert.Equal(t, "Service", req["type"])
+	assert.Equal(t, `host.name == "t1-web1"`, req["filter"])
+	assert.Equal(t, "ops", req["author"])
+	assert.Equal(t, true, req["sticky"])
+	assert.Equal(t, false, req["notify"])
+	assert.Equal(t, float64(expiry.Unix()), req["expiry"])
+}
+
+func TestAPI_AcknowledgeProblem_Invalid(t *testing.T) {
+	Api, err := New("http://127.0.0.1:1", TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.AcknowledgeProblem("Host", "true", Acknowledgement{Author: "ops"}, ActionOptions{})
+	assert.EqualError(t, err, "acknowledgement needs author and comment")
+	_, err = Api.AcknowledgeProblem("Host", "true", Acknowledgement{Author: "ops", Comment: "c", Expiry: time.Now().Add(-time.Hour)}, ActionOptions{})
+	assert.EqualError(t, err, "acknowledgement expiry is in the past")
+	_, err = Api.AddComment("Zone", "true", "ops", "c", ActionOptions{})
+	assert.EqualError(t, err, "object type has to be Host or Service, not [Zone]")
+	_, err = Api.RescheduleCheck("Host", "", time.Time{}, false, ActionOptions{})
+	assert.EqualError(t, err, "action reschedule-check needs filter")
+}
+
+func TestAPI_RescheduleCheck(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/actions/reschedule-check": "v1.actions.reschedule-check.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	results, err := Api.RescheduleCheck("Host", `host.name == "t1-web1"`, time.Time{}, true, ActionOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"t1-web1"}, results.Objects())
+	require.Len(t, ts.Requests(), 1)
+	assert.Contains(t, ts.Requests()[0].Body, `"force":true`)
+	assert.NotContains(t, ts.Requests()[0].Body, "next_check", "Icinga2 defaults to now")
+}
+
+func TestProxy_AcknowledgeProblem(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/actions/acknowledge-problem": "v1.actions.acknowledge-problem.json"})
+	defer ts.Close()
+	Api, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	results, err := Api.AcknowledgeProblem("Service", `host.name == "t1-web1"`, Acknowledgement{Author: "ops", Comment: "c"}, ActionOptions{})
+	require.Nil(t, err)
+	require.Len(t, results, 4)
+	assert.Equal(t, "s1", results[0].Server)
+	assert.Equal(t, "s2", results[3].Server)
+	assert.EqualError(t, results.Err(), "action failed for 2 of 4 objects: s1/t1-web1!DISK: [409] No problems to acknowledge for object 't1-web1!DISK'.; s2/t1-web1!DISK: [409] No problems to acknowledge for object 't1-web1!DISK'.")
+}
+
+func TestProxy_AddComment_Validate(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{})
+	defer ts.Close()
+	Api, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	_, err = Api.AddComment("Host", `host.name == "t1-web1"`, "", "c", ActionOptions{DryRun: true})
+	assert.EqualError(t, err, "comment needs author and text")
+	assert.Empty(t, ts.Requests(), "nothing should be previewed")
+}
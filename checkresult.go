@@ -0,0 +1,132 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CheckResult is the result of single check execution
+type CheckResult struct {
+	// endpoint that executed the check
+	CheckFrom       string      `json:"check_source"`
+	Message         string      `json:"output"`
+	PerformanceData RawPerfdata `json:"performance_data"`
+	// false for passive check results
+	Active     bool        `json:"active"`
+	Command    CommandLine `json:"command"`
+	ExitStatus float64     `json:"exit_status"`
+	State      float64     `json:"state"`
+	// time for which passive check result is valid, in seconds
+	TTL            float64          `json:"ttl"`
+	ExecutionStart Timestamp        `json:"execution_start"`
+	ExecutionEnd   Timestamp        `json:"execution_end"`
+	ScheduleStart  Timestamp        `json:"schedule_start"`
+	ScheduleEnd    Timestamp        `json:"schedule_end"`
+	VarsBefore     *CheckResultVars `json:"vars_before"`
+	VarsAfter      *CheckResultVars `json:"vars_after"`
+	// set when performance_data was in unknown format
+	perfdataErr error
+}
+
+// UnmarshalJSON decodes check result. Performance data in unknown format doesn't fail decoding of the whole object,
+// the error is returned by Perfdata instead
+func (c *CheckResult) UnmarshalJSON(b []byte) error {
+	type checkResult CheckResult
+	var r struct {
+		checkResult
+		PerformanceData json.RawMessage `json:"performance_data"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	*c = CheckResult(r.checkResult)
+	if len(r.PerformanceData) > 0 {
+		if err := json.Unmarshal(r.PerformanceData, &c.PerformanceData); err != nil {
+			c.PerformanceData = nil
+			c.perfdataErr = fmt.Errorf("error decoding performance data %s: %s", string(r.PerformanceData), err)
+		}
+	}
+	return nil
+}
+
+// CheckResultVars is the object state before and after processing check result
+type CheckResultVars struct {
+	Attempt   float64 `json:"attempt"`
+	Reachable bool    `json:"reachable"`
+	State     float64 `json:"state"`
+	StateType float64 `json:"state_type"`
+}
+
+// CommandLine is the executed command. API returns either list of arguments or a single string for shell commands
+type CommandLine []string
+
+func (c *CommandLine) UnmarshalJSON(b []byte) error {
+	var single *string
+	if err := json.Unmarshal(b, &single); err == nil {
+		if single == nil {
+			*c = nil
+		} else {
+			*c = CommandLine{*single}
+		}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("command should be string or list of strings: %s", err)
+	}
+	*c = list
+	return nil
+}
+
+// Perfdata returns parsed performance data of the check
+func (c *CheckResult) Perfdata() ([]Perfdata, error) {
+	if c.perfdataErr != nil {
+		return nil, c.perfdataErr
+	}
+	return c.PerformanceData.Parse()
+}
+
+// ExecutionTime returns how long the check command was running
+func (c *CheckResult) ExecutionTime() time.Duration {
+	if c.ExecutionStart.IsZero() || c.ExecutionEnd.IsZero() {
+		return 0
+	}
+	return c.ExecutionEnd.Sub(c.ExecutionStart.Time)
+}
+
+// Latency returns time spent by Icinga2 scheduling the check and processing its result, that is schedule duration minus execution time
+func (c *CheckResult) Latency() time.Duration {
+	if c.ScheduleStart.IsZero() || c.ScheduleEnd.IsZero() {
+		return 0
+	}
+	latency := c.ScheduleEnd.Sub(c.ScheduleStart.Time) - c.ExecutionTime()
+	if latency < 0 {
+		return 0
+	}
+	return latency
+}
+
+// GetHostCheckResult returns last check result of the host
+func (a *API) GetHostCheckResult(host string) (*CheckResult, error) {
+	hosts, err := a.GetHostsDetailed("host.name==" + quoteString(host))
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("host [%s] not found", host)
+	}
+	return &hosts[0].LastCheckResult, nil
+}
+
+// GetServiceCheckResult returns last check result of the service
+func (a *API) GetServiceCheckResult(host string, service string) (*CheckResult, error) {
+	services, err := a.GetServicesDetailed("host.name==" + quoteString(host) + " && service.name==" + quoteString(service))
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("service [%s!%s] not found", host, service)
+	}
+	return &services[0].LastCheckResult, nil
+}
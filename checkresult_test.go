@@ -0,0 +1,69 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAPI_GetHostCheckResult(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "v1.objects.host.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	cr, err := Api.GetHostCheckResult("t1-host1")
+	require.Nil(t, err)
+	assert.Equal(t, `host.name=="t1-host1"`, ts.LastQuery().Get("filter"))
+	assert.Equal(t, "t1-z2.example.com", cr.CheckFrom)
+	assert.True(t, cr.Active)
+	assert.Len(t, cr.Command, 7)
+	assert.Equal(t, "/usr/lib/nagios/plugins/check_ping", cr.Command[0])
+	assert.Equal(t, float64(0), cr.ExitStatus)
+	assert.InDelta(t, 4.0496, cr.ExecutionTime().Seconds(), 0.0001)
+	assert.InDelta(t, 0.00075, cr.Latency().Seconds(), 0.0001)
+	require.NotNil(t, cr.VarsAfter)
+	assert.True(t, cr.VarsAfter.Reachable)
+	assert.Equal(t, float64(1), cr.VarsAfter.StateType)
+	perfdata, err := cr.Perfdata()
+	require.Nil(t, err)
+	assert.Len(t, perfdata, 2)
+}
+
+func TestAPI_GetServiceCheckResult(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Services": "v1.objects.services.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	cr, err := Api.GetServiceCheckResult("t1-host1", `say "hi"`)
+	require.Nil(t, err)
+	assert.Equal(t, `host.name=="t1-host1" && service.name=="say \"hi\""`, ts.LastQuery().Get("filter"))
+	assert.Equal(t, "t1-mon1.example.com", cr.CheckFrom)
+}
+
+func TestAPI_GetHostCheckResult_NotFound(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "v1.objects.empty.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.GetHostCheckResult("t1-host1")
+	assert.Error(t, err)
+}
+
+func TestCheckResult_Decode(t *testing.T) {
+	var c CheckResult
+	err := json.Unmarshal([]byte(`{"command":"/bin/true --quiet","execution_start":10,"execution_end":12.5,"schedule_start":9,"schedule_end":13}`), &c)
+	require.Nil(t, err)
+	assert.Equal(t, CommandLine{"/bin/true --quiet"}, c.Command)
+	assert.Equal(t, 2500*time.Millisecond, c.ExecutionTime())
+	assert.Equal(t, 1500*time.Millisecond, c.Latency())
+	err = json.Unmarshal([]byte(`{"command":null}`), &c)
+	require.Nil(t, err)
+	assert.Nil(t, c.Command)
+	assert.Equal(t, time.Duration(0), (&CheckResult{}).Latency())
+}
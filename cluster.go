@@ -0,0 +1,159 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Zone is Icinga2 Zone object
+type Zone struct {
+	Name      string   `json:"name"`
+	Endpoints []string `json:"endpoints"`
+	// empty for top level zone
+	Parent string `json:"parent"`
+	// global zones only sync config and have no endpoints
+	Global bool `json:"global"`
+}
+
+// Endpoint is Icinga2 Endpoint object with its connection state
+type Endpoint struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port string `json:"port"`
+	Zone string `json:"zone"`
+	// how long replay log is kept, in seconds
+	LogDuration float64 `json:"log_duration"`
+	Connected   bool    `json:"connected"`
+	Connecting  bool    `json:"connecting"`
+	Syncing     bool    `json:"syncing"`
+
+	LastMessageSent           Timestamp `json:"last_message_sent"`
+	LastMessageReceived       Timestamp `json:"last_message_received"`
+	MessagesSentPerSecond     float64   `json:"messages_sent_per_second"`
+	MessagesReceivedPerSecond float64   `json:"messages_received_per_second"`
+	BytesSentPerSecond        float64   `json:"bytes_sent_per_second"`
+	BytesReceivedPerSecond    float64   `json:"bytes_received_per_second"`
+	// replay log positions, unix timestamps of the last message written locally and acknowledged by remote
+	LocalLogPosition  Timestamp `json:"local_log_position"`
+	RemoteLogPosition Timestamp `json:"remote_log_position"`
+}
+
+// ClusterHealth summarizes connection state of the cluster as seen by single node
+type ClusterHealth struct {
+	// node the data was read from, it is never connected to itself
+	LocalNode             string   `json:"local_node"`
+	Zones                 int      `json:"zones"`
+	Endpoints             int      `json:"endpoints"`
+	ConnectedEndpoints    int      `json:"connected_endpoints"`
+	DisconnectedEndpoints []string `json:"disconnected_endpoints"`
+	// non-global zones with no connected (or local) endpoint
+	DisconnectedZones []string `json:"disconnected_zones"`
+}
+
+// Healthy returns true if all endpoints and zones are connected
+func (c *ClusterHealth) Healthy() bool {
+	return len(c.DisconnectedEndpoints) == 0 && len(c.DisconnectedZones) == 0
+}
+
+// SummarizeCluster checks which endpoints and zones are disconnected, localNode is treated as connected
+func SummarizeCluster(localNode string, zones []Zone, endpoints []Endpoint) ClusterHealth {
+	c := ClusterHealth{
+		LocalNode:             localNode,
+		Endpoints:             len(endpoints),
+		DisconnectedEndpoints: make([]string, 0),
+		DisconnectedZones:     make([]string, 0),
+	}
+	connected := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		if e.Connected || e.Name == localNode {
+			connected[e.Name] = true
+			c.ConnectedEndpoints++
+		} else {
+			c.DisconnectedEndpoints = append(c.DisconnectedEndpoints, e.Name)
+		}
+	}
+	for _, z := range zones {
+		if z.Global {
+			continue
+		}
+		c.Zones++
+		ok := false
+		for _, e := range z.Endpoints {
+			if connected[e] {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			c.DisconnectedZones = append(c.DisconnectedZones, z.Name)
+		}
+	}
+	sort.Strings(c.DisconnectedEndpoints)
+	sort.Strings(c.DisconnectedZones)
+	return c
+}
+
+func (i *Icinga2APIResponse) GetZones() (v []Zone) {
+	for _, obj := range i.Results {
+		if obj.Type != "Zone" {
+			continue
+		}
+		var zone Zone
+		err := json.Unmarshal(obj.Attrs, &zone)
+		if err != nil {
+			log.Printf("error unmarshalling zone %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		v = append(v, zone)
+	}
+	return v
+}
+
+func (i *Icinga2APIResponse) GetEndpoints() (v []Endpoint) {
+	for _, obj := range i.Results {
+		if obj.Type != "Endpoint" {
+			continue
+		}
+		var endpoint Endpoint
+		err := json.Unmarshal(obj.Attrs, &endpoint)
+		if err != nil {
+			log.Printf("error unmarshalling endpoint %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		v = append(v, endpoint)
+	}
+	return v
+}
+
+func (a *API) GetZones() (m []Zone, err error) {
+	i, err := a.getObjects("Zones", QueryOptions{})
+	if err != nil {
+		return m, err
+	}
+	return i.GetZones(), nil
+}
+
+func (a *API) GetEndpoints() (m []Endpoint, err error) {
+	i, err := a.getObjects("Endpoints", QueryOptions{})
+	if err != nil {
+		return m, err
+	}
+	return i.GetEndpoints(), nil
+}
+
+// ClusterHealth returns connection state of the cluster as seen by the node API is connected to
+func (a *API) ClusterHealth() (c ClusterHealth, err error) {
+	node, err := a.NodeName()
+	if err != nil {
+		return c, err
+	}
+	zones, err := a.GetZones()
+	if err != nil {
+		return c, err
+	}
+	endpoints, err := a.GetEndpoints()
+	if err != nil {
+		return c, err
+	}
+	return SummarizeCluster(node, zones, endpoints), nil
+}
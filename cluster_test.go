@@ -0,0 +1,70 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_GetZones(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Zones", "v1.objects.zones.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	zones, err := Api.GetZones()
+	require.Nil(t, err)
+	require.Len(t, zones, 4)
+	assert.Equal(t, "master", zones[1].Parent)
+	assert.Equal(t, []string{"t1-z1.example.com"}, zones[1].Endpoints)
+	assert.True(t, zones[3].Global)
+}
+
+func TestAPI_GetEndpoints(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Endpoints", "v1.objects.endpoints.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	endpoints, err := Api.GetEndpoints()
+	require.Nil(t, err)
+	require.Len(t, endpoints, 4)
+	assert.True(t, endpoints[1].Connected)
+	assert.Equal(t, int64(1619107422), endpoints[1].LastMessageReceived.Unix())
+	assert.Equal(t, int64(1619107421), endpoints[1].RemoteLogPosition.Unix())
+	assert.Equal(t, 30.1, endpoints[1].MessagesReceivedPerSecond)
+	assert.True(t, endpoints[3].Connecting)
+	assert.True(t, endpoints[0].LastMessageSent.IsZero())
+}
+
+func TestAPI_ClusterHealth(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/variables/NodeName": "v1.variables.NodeName.json",
+		"/v1/objects/Zones":      "v1.objects.zones.json",
+		"/v1/objects/Endpoints":  "v1.objects.endpoints.json",
+	})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	c, err := Api.ClusterHealth()
+	require.Nil(t, err)
+	assert.Equal(t, "t1-mon1.example.com", c.LocalNode)
+	assert.Equal(t, 3, c.Zones, "global zone is skipped")
+	assert.Equal(t, 4, c.Endpoints)
+	assert.Equal(t, 3, c.ConnectedEndpoints, "local node counts as connected")
+	assert.Equal(t, []string{"t1-z2.example.com"}, c.DisconnectedEndpoints)
+	assert.Equal(t, []string{"t1-z2"}, c.DisconnectedZones)
+	assert.False(t, c.Healthy())
+}
+
+func TestSummarizeCluster(t *testing.T) {
+	zones := []Zone{{Name: "master", Endpoints: []string{"m1", "m2"}}, {Name: "sat", Endpoints: []string{"s1", "s2"}, Parent: "master"}}
+	endpoints := []Endpoint{{Name: "m1"}, {Name: "m2"}, {Name: "s1"}, {Name: "s2", Connected: true}}
+	c := SummarizeCluster("m1", zones, endpoints)
+	assert.Equal(t, []string{"m2", "s1"}, c.DisconnectedEndpoints)
+	assert.Empty(t, c.DisconnectedZones, "zone with one connected endpoint is connected")
+	c = SummarizeCluster("m1", zones, []Endpoint{{Name: "m1"}, {Name: "m2", Connected: true}, {Name: "s1", Connected: true}, {Name: "s2", Connected: true}})
+	assert.True(t, c.Healthy())
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"github.com/efigence/go-monitoring"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type command struct {
+	usage string
+	run   func(p *icinga2.Proxy, out *output, args []string) error
+}
+
+var commands = map[string]command{
+	"hosts":      {"list hosts", cmdHosts},
+	"services":   {"list services", cmdServices},
+	"problems":   {"list hosts and services in non-OK state", cmdProblems},
+	"downtime":   {"schedule, list or remove downtimes: downtime schedule|list|remove", cmdDowntime},
+	"ack":        {"acknowledge problems or remove acknowledgement with -remove", cmdAck},
+	"comment":    {"add comment to hosts or services", cmdComment},
+	"reschedule": {"run checks now or at given time", cmdReschedule},
+	"status":     {"show version and global flags of every server", cmdStatus},
+}
+
+// selection builds action target from host/service names and raw filter
+type selection struct {
+	objType string
+	host    string
+	service string
+	filter  string
+}
+
+func (s *selection) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.objType, "type", "", "object type, host or service. Defaults to service if -service is set")
+	fs.StringVar(&s.host, "host", "", "host name, * wildcards are allowed")
+	fs.StringVar(&s.service, "service", "", "service name, * wildcards are allowed")
+	fs.StringVar(&s.filter, "filter", "", "Icinga2 filter expression, combined with -host and -service")
+}
+
+func nameFilter(attr string, name string) icinga2.Filter {
+	if strings.Contains(name, "*") {
+		return icinga2.Match(name, attr)
+	}
+	return icinga2.Eq(attr, name)
+}
+
+// target returns Icinga2 object type and filter
+func (s *selection) target() (objType string, filter string, err error) {
+	switch strings.ToLower(s.objType) {
+	case "":
+		objType = "Host"
+		if s.service != "" {
+			objType = "Service"
+		}
+	case "host":
+		objType = "Host"
+	case "service":
+		objType = "Service"
+	default:
+		return "", "", fmt.Errorf("unknown type [%s], should be host or service", s.objType)
+	}
+	if objType == "Host" && s.service != "" {
+		return "", "", fmt.Errorf("-service can't be used with host type")
+	}
+	filters := make([]icinga2.Filter, 0, 3)
+	if s.host != "" {
+		filters = append(filters, nameFilter("host.name", s.host))
+	}
+	if s.service != "" {
+		filters = append(filters, nameFilter("service.name", s.service))
+	}
+	if s.filter != "" {
+		filters = append(filters, icinga2.RawFilter(s.filter))
+	}
+	if len(filters) == 0 {
+		return "", "", fmt.Errorf("select objects with -host, -service or -filter")
+	}
+	return objType, icinga2.And(filters...).String(), nil
+}
+
+// actionFlags are common to all actions changing objects
+type actionFlags struct {
+	dryRun bool
+	max    int
+}
+
+func (a *actionFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&a.dryRun, "dry-run", false, "only list objects the action would be executed on")
+	fs.IntVar(&a.max, "max", 0, "abort if more objects are matched, 0 means no limit")
+}
+
+func (a *actionFlags) options() icinga2.ActionOptions {
+	return icinga2.ActionOptions{DryRun: a.dryRun, MaxObjects: a.max}
+}
+
+func defaultAuthor() string {
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	return "icinga2ctl"
+}
+
+func hostStateName(state uint8) string {
+	switch state {
+	case monitoring.HostUp:
+		return "UP"
+	case monitoring.HostDown:
+		return "DOWN"
+	case monitoring.HostUnreachable:
+		return "UNREACHABLE"
+	}
+	return "INVALID"
+}
+
+func serviceStateName(state uint8) string {
+	switch state {
+	case monitoring.StatusOk:
+		return "OK"
+	case monitoring.StatusWarning:
+		return "WARNING"
+	case monitoring.StatusCritical:
+		return "CRITICAL"
+	case monitoring.StatusUnknown:
+		return "UNKNOWN"
+	}
+	return "INVALID"
+}
+
+func cmdHosts(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("hosts", flag.ContinueOnError)
+	filter := fs.String("filter", "", "Icinga2 filter expression")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	hosts, err := p.GetHostsAnnotated(*filter)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"SERVER", "HOST", "STATE", "HARD", "ACK", "DOWNTIME", "SINCE", "MESSAGE"}, data: hosts}
+	for _, h := range hosts {
+		t.add(h.Server, h.Host.Host, hostStateName(h.State), formatBool(h.StateHard), formatBool(h.Acknowledged),
+			formatBool(h.Downtime), formatTime(h.LastStateChange), out.truncate(h.CheckMessage, 60))
+	}
+	return out.write(t)
+}
+
+func cmdServices(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("services", flag.ContinueOnError)
+	filter := fs.String("filter", "", "Icinga2 filter expression")
+	host := fs.String("host", "", "only services of host, * wildcards are allowed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	f := *filter
+	if *host != "" {
+		filters := []icinga2.Filter{nameFilter("host.name", *host)}
+		if f != "" {
+			filters = append(filters, icinga2.RawFilter(f))
+		}
+		f = icinga2.And(filters...).String()
+	}
+	services, err := p.GetServicesAnnotated(f)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"SERVER", "HOST", "SERVICE", "STATE", "HARD", "ACK", "DOWNTIME", "SINCE", "MESSAGE"}, data: services}
+	for _, s := range services {
+		t.add(s.Server, s.Host, s.Service.Service, serviceStateName(s.State), formatBool(s.StateHard), formatBool(s.Acknowledged),
+			formatBool(s.Downtime), formatTime(s.LastStateChange), out.truncate(s.CheckMessage, 60))
+	}
+	return out.write(t)
+}
+
+func cmdProblems(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("problems", flag.ContinueOnError)
+	var opts icinga2.ProblemOptions
+	fs.StringVar(&opts.Filter, "filter", "", "Icinga2 filter expression applied to hosts and services")
+	fs.BoolVar(&opts.UnhandledOnly, "unhandled", false, "skip acknowledged and downtimed problems")
+	fs.BoolVar(&opts.HardOnly, "hard", false, "skip problems in soft state")
+	fs.BoolVar(&opts.NoHosts, "no-hosts", false, "skip host problems")
+	fs.BoolVar(&opts.NoServices, "no-services", false, "skip service problems")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	problems, err := p.Problems(opts)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"SERVER", "HOST", "SERVICE", "STATE", "HARD", "HANDLED", "DURATION", "MESSAGE"}, data: problems}
+	for _, pr := range problems {
+		state := serviceStateName(pr.State)
+		if pr.IsHost() {
+			state = hostStateName(pr.State)
+		}
+		t.add(pr.Server, pr.Host, pr.Service, state, formatBool(pr.Hard), formatBool(pr.Handled),
+			pr.Duration.Truncate(time.Second).String(), out.truncate(pr.Message, 60))
+	}
+	return out.write(t)
+}
+
+// writeResults prints action results and returns error if action failed for any object
+func writeResults(out *output, results icinga2.ActionResults) error {
+	t := &table{header: []string{"SERVER", "OBJECT", "CODE", "STATUS", "NAME"}, data: results}
+	for _, r := range results {
+		code := strconv.Itoa(r.Code)
+		if r.DryRun {
+			code = "-"
+		}
+		t.add(r.Server, r.Object, code, r.Status, r.Name)
+	}
+	err := out.write(t)
+	if err != nil {
+		return err
+	}
+	return results.Err()
+}
+
+func cmdDowntime(p *icinga2.Proxy, out *output, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("downtime needs subcommand: schedule, list or remove")
+	}
+	switch args[0] {
+	case "schedule":
+		return cmdDowntimeSchedule(p, out, args[1:])
+	case "list":
+		return cmdDowntimeList(p, out, args[1:])
+	case "remove":
+		return cmdDowntimeRemove(p, out, args[1:])
+	}
+	return fmt.Errorf("unknown downtime subcommand [%s], should be one of schedule, list, remove", args[0])
+}
+
+func cmdDowntimeSchedule(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("downtime schedule", flag.ContinueOnError)
+	var sel selection
+	var action actionFlags
+	sel.register(fs)
+	action.register(fs)
+	start := fs.String("start", "", "start time in RFC3339 format, now if empty")
+	duration := fs.Duration("duration", time.Hour, "length of the downtime window")
+	flexible := fs.Duration("flexible", 0, "schedule flexible downtime lasting that long after problem starts within the window")
+	noServices := fs.Bool("no-services", false, "don't put services of downtimed hosts in downtime")
+	author := fs.String("author", defaultAuthor(), "downtime author")
+	comment := fs.String("comment", "", "downtime comment")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	objType, filter, err := sel.target()
+	if err != nil {
+		return err
+	}
+	if *comment == "" {
+		return fmt.Errorf("downtime needs -comment")
+	}
+	startTime := time.Now()
+	if *start != "" {
+		startTime, err = time.Parse(time.RFC3339, *start)
+		if err != nil {
+			return fmt.Errorf("invalid -start: %s", err)
+		}
+	}
+	downtime := icinga2.Downtime{
+		Flexible:      *flexible > 0,
+		Start:         startTime,
+		End:           startTime.Add(*duration),
+		Duration:      *flexible,
+		NoAllServices: *noServices,
+		Author:        *author,
+		Comment:       *comment,
+	}
+	results, err := p.ScheduleDowntime(icinga2.DowntimeTarget{Type: objType, Filter: filter}, downtime, action.options())
+	if err != nil {
+		return err
+	}
+	return writeResults(out, results)
+}
+
+func cmdDowntimeList(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("downtime list", flag.ContinueOnError)
+	filter := fs.String("filter", "", "Icinga2 filter expression, e.g. downtime.author == \"ops\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	downtimes, err := p.GetDowntimes(*filter)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"SERVER", "OBJECT", "START", "END", "FIXED", "AUTHOR", "COMMENT", "NAME"}, data: downtimes}
+	for _, d := range downtimes {
+		t.add(d.Server, d.Object(), formatTime(d.StartTime.Time), formatTime(d.EndTime.Time), formatBool(d.Fixed),
+			d.Author, out.truncate(d.Comment, 40), d.FullName)
+	}
+	return out.write(t)
+}
+
+func cmdDowntimeRemove(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("downtime remove", flag.ContinueOnError)
+	var action actionFlags
+	action.register(fs)
+	name := fs.String("name", "", "full downtime name as shown by downtime list")
+	filter := fs.String("filter", "", "Icinga2 filter expression selecting downtimes, e.g. host.name == \"web1\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var results icinga2.ActionResults
+	var err error
+	switch {
+	case *name != "" && *filter != "":
+		return fmt.Errorf("use either -name or -filter")
+	case *name != "":
+		if action.dryRun || action.max > 0 {
+			return fmt.Errorf("-dry-run and -max can only be used with -filter")
+		}
+		results, err = p.RemoveDowntime(*name)
+	case *filter != "":
+		results, err = p.RemoveDowntimesByFilter(*filter, action.options())
+	default:
+		return fmt.Errorf("select downtimes with -name or -filter")
+	}
+	if err != nil {
+		return err
+	}
+	return writeResults(out, results)
+}
+
+func cmdAck(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("ack", flag.ContinueOnError)
+	var sel selection
+	var action actionFlags
+	sel.register(fs)
+	action.register(fs)
+	var ack icinga2.Acknowledgement
+	fs.StringVar(&ack.Author, "author", defaultAuthor(), "acknowledgement author")
+	fs.StringVar(&ack.Comment, "comment", "", "acknowledgement comment")
+	expire := fs.Duration("expire", 0, "remove acknowledgement after that time, never if 0")
+	fs.BoolVar(&ack.Sticky, "sticky", false, "keep acknowledgement until object recovers")
+	fs.BoolVar(&ack.Notify, "notify", false, "send acknowledgement notification")
+	fs.BoolVar(&ack.Persistent, "persistent", false, "keep comment after acknowledgement is removed")
+	remove := fs.Bool("remove", false, "remove acknowledgement instead of adding it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	objType, filter, err := sel.target()
+	if err != nil {
+		return err
+	}
+	var results icinga2.ActionResults
+	if *remove {
+		results, err = p.RemoveAcknowledgement(objType, filter, action.options())
+	} else {
+		if *expire > 0 {
+			ack.Expiry = time.Now().Add(*expire)
+		}
+		results, err = p.AcknowledgeProblem(objType, filter, ack, action.options())
+	}
+	if err != nil {
+		return err
+	}
+	return writeResults(out, results)
+}
+
+func cmdComment(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("comment", flag.ContinueOnError)
+	var sel selection
+	var action actionFlags
+	sel.register(fs)
+	action.register(fs)
+	author := fs.String("author", defaultAuthor(), "comment author")
+	comment := fs.String("comment", "", "comment text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	objType, filter, err := sel.target()
+	if err != nil {
+		return err
+	}
+	results, err := p.AddComment(objType, filter, *author, *comment, action.options())
+	if err != nil {
+		return err
+	}
+	return writeResults(out, results)
+}
+
+func cmdReschedule(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("reschedule", flag.ContinueOnError)
+	var sel selection
+	var action actionFlags
+	sel.register(fs)
+	action.register(fs)
+	delay := fs.Duration("in", 0, "run check after that time instead of now")
+	force := fs.Bool("force", false, "run check even if active checks are disabled or outside of check period")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	objType, filter, err := sel.target()
+	if err != nil {
+		return err
+	}
+	var next time.Time
+	if *delay > 0 {
+		next = time.Now().Add(*delay)
+	}
+	results, err := p.RescheduleCheck(objType, filter, next, *force, action.options())
+	if err != nil {
+		return err
+	}
+	return writeResults(out, results)
+}
+
+func cmdStatus(p *icinga2.Proxy, out *output, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	servers := p.Servers()
+	t := &table{header: []string{"SERVER", "NODE", "VERSION", "STARTED", "NOTIFICATIONS", "HOST_CHECKS", "SERVICE_CHECKS", "ERROR"}}
+	type serverStatus struct {
+		icinga2.ApplicationStatus
+		Error string `json:"error,omitempty"`
+	}
+	data := make([]serverStatus, 0, len(servers))
+	failed := 0
+	// servers are asked one by one as status of every server is shown, including failed ones
+	for _, server := range sortedKeys(servers) {
+		s, err := servers[server].GetApplicationStatus()
+		if err != nil {
+			failed++
+			data = append(data, serverStatus{ApplicationStatus: icinga2.ApplicationStatus{Server: server}, Error: err.Error()})
+			t.add(server, "-", "-", "-", "-", "-", "-", err.Error())
+			continue
+		}
+		s.Server = server
+		data = append(data, serverStatus{ApplicationStatus: s})
+		t.add(s.Server, s.NodeName, s.Version, formatTime(s.ProgramStart.Time), formatBool(s.EnableNotifications),
+			formatBool(s.EnableHostChecks), formatBool(s.EnableServiceChecks), "")
+	}
+	t.data = data
+	err := out.write(t)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d servers failed", failed, len(servers))
+	}
+	return nil
+}
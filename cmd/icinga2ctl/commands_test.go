@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSelection_Target(t *testing.T) {
+	objType, filter, err := (&selection{host: "web*", service: "HTTP"}).target()
+	require.Nil(t, err)
+	assert.Equal(t, "Service", objType)
+	assert.Equal(t, `(match("web*", host.name)) && (service.name=="HTTP")`, filter)
+
+	objType, filter, err = (&selection{objType: "host", filter: `"linux" in host.groups`}).target()
+	require.Nil(t, err)
+	assert.Equal(t, "Host", objType)
+	assert.Equal(t, `"linux" in host.groups`, filter)
+
+	_, _, err = (&selection{}).target()
+	assert.NotNil(t, err, "empty selection would match everything")
+	_, _, err = (&selection{objType: "host", service: "HTTP"}).target()
+	assert.NotNil(t, err)
+}
+
+func TestRun_Downtime(t *testing.T) {
+	ts := icingatest.NewServer(t, "../../testdata", map[string]string{"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json"})
+	defer ts.Close()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-url", ts.URL, "-o", "csv", "downtime", "schedule", "-host", "t1-mon*", "-comment", "upgrade", "-author", "ops"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	require.Len(t, ts.Requests(), 1)
+	assert.Contains(t, ts.Requests()[0].Body, `"filter":"match(\"t1-mon*\", host.name)"`)
+	assert.Contains(t, ts.Requests()[0].Body, `"author":"ops"`)
+	assert.Contains(t, ts.Requests()[0].Body, `"fixed":true`)
+
+	var flexOut, flexErr bytes.Buffer
+	code = run([]string{"-url", ts.URL, "downtime", "schedule", "-host", "t1-mon1", "-comment", "upgrade", "-flexible", "10m"}, &flexOut, &flexErr)
+	require.Equal(t, 0, code, flexErr.String())
+	require.Len(t, ts.Requests(), 2)
+	assert.Contains(t, ts.Requests()[1].Body, `"fixed":false`)
+	assert.Contains(t, ts.Requests()[1].Body, `"duration":600`)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "SERVER,OBJECT,CODE,STATUS,NAME", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "default,t1-mon1,200,"), lines[1])
+}
+
+func TestRun_Ack_Failed(t *testing.T) {
+	ts := icingatest.NewServer(t, "../../testdata", map[string]string{"/v1/actions/acknowledge-problem": "v1.actions.acknowledge-problem.json"})
+	defer ts.Close()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-url", ts.URL, "ack", "-host", "t1-web1", "-service", "*", "-comment", "on it"}, &stdout, &stderr)
+	assert.Equal(t, 1, code, "action failed for one of the objects")
+	assert.Contains(t, stdout.String(), "t1-web1!HTTP")
+	assert.Contains(t, stderr.String(), "action failed for 1 of 2 objects")
+}
+
+func TestRun_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run([]string{}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "problems")
+	assert.Equal(t, 2, run([]string{"-url", "http://127.0.0.1:1", "nope"}, &stdout, &stderr))
+	assert.Equal(t, 2, run([]string{"hosts"}, &stdout, &stderr), "no server configured")
+}
@@ -0,0 +1,102 @@
+// icinga2ctl runs everyday operations (listing problems, downtimes, acknowledgements...) on one or more Icinga2 servers
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"io"
+	"os"
+	"sort"
+)
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: %s [options] <command> [command options]\n\nCommands:\n", fs.Name())
+		for _, name := range sortedKeys(commands) {
+			fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].usage)
+		}
+		fmt.Fprintf(w, "\nRun \"%s <command> -h\" for command options.\n\nOptions:\n", fs.Name())
+		fs.PrintDefaults()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// proxyConfig loads config file or creates single server config from flags
+func proxyConfig(path string, server icinga2.Icinga2ServerConfig) (*icinga2.ProxyConfig, error) {
+	if path != "" {
+		return icinga2.LoadProxyConfig(path)
+	}
+	if server.ServerURL == "" {
+		return nil, fmt.Errorf("set -config or -url (or ICINGA2_URL)")
+	}
+	cfg := &icinga2.ProxyConfig{Servers: map[string]icinga2.Icinga2ServerConfig{"default": server}}
+	return cfg, cfg.Validate()
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("icinga2ctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = usage(fs)
+	var server icinga2.Icinga2ServerConfig
+	configPath := fs.String("config", os.Getenv("ICINGA2CTL_CONFIG"), "proxy config file (YAML or JSON) with servers to use")
+	fs.StringVar(&server.ServerURL, "url", os.Getenv("ICINGA2_URL"), "API URL of single server, e.g. https://icinga:5665")
+	fs.StringVar(&server.User, "user", os.Getenv("ICINGA2_USER"), "API user")
+	fs.StringVar(&server.Pass, "pass", os.Getenv("ICINGA2_PASS"), "API password")
+	fs.StringVar(&server.CACert, "ca", "", "CA certificate used to verify server")
+	fs.BoolVar(&server.InsecureSkipVerify, "insecure", false, "don't verify server certificate")
+	format := fs.String("o", "table", "output format: table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command [%s]\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	out, err := newOutput(stdout, *format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cfg, err := proxyConfig(*configPath, server)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	proxy, err := icinga2.NewProxyFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	err = cmd.run(proxy, out, fs.Args()[1:])
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
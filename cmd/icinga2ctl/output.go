@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// table is command output, rows are used for table and CSV formats, data for JSON
+type table struct {
+	header []string
+	rows   [][]string
+	data   interface{}
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+type output struct {
+	w      io.Writer
+	format string
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case "table", "json", "csv":
+	default:
+		return nil, fmt.Errorf("unknown output format [%s], should be one of table, json, csv", format)
+	}
+	return &output{w: w, format: format}, nil
+}
+
+func (o *output) write(t *table) error {
+	switch o.format {
+	case "json":
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t.data)
+	case "csv":
+		w := csv.NewWriter(o.w)
+		err := w.Write(t.header)
+		if err != nil {
+			return err
+		}
+		err = w.WriteAll(t.rows)
+		if err != nil {
+			return err
+		}
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			cells := make([]string, len(row))
+			for i, c := range row {
+				// tabs and newlines in check output would break the columns
+				cells[i] = strings.NewReplacer("\t", " ", "\n", " ", "\r", "").Replace(c)
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// truncate shortens s to n runes in table output, CSV and JSON get the full text
+func (o *output) truncate(s string, n int) string {
+	if o.format != "table" {
+		return s
+	}
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i]
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func testTable() *table {
+	t := &table{header: []string{"HOST", "MESSAGE"}, data: []map[string]string{{"host": "web1"}}}
+	t.add("web1", "PING OK,\tall good")
+	t.add("db1", "multi\nline")
+	return t
+}
+
+func TestOutput_Table(t *testing.T) {
+	var b bytes.Buffer
+	out, err := newOutput(&b, "table")
+	require.Nil(t, err)
+	require.Nil(t, out.write(testTable()))
+	assert.Equal(t, "HOST  MESSAGE\nweb1  PING OK, all good\ndb1   multi line\n", b.String())
+}
+
+func TestOutput_CSV(t *testing.T) {
+	var b bytes.Buffer
+	out, err := newOutput(&b, "csv")
+	require.Nil(t, err)
+	require.Nil(t, out.write(testTable()))
+	assert.Equal(t, "HOST,MESSAGE\nweb1,\"PING OK,\tall good\"\ndb1,\"multi\nline\"\n", b.String())
+}
+
+func TestOutput_JSON(t *testing.T) {
+	var b bytes.Buffer
+	out, err := newOutput(&b, "json")
+	require.Nil(t, err)
+	require.Nil(t, out.write(testTable()))
+	assert.JSONEq(t, `[{"host":"web1"}]`, b.String())
+}
+
+func TestOutput_Invalid(t *testing.T) {
+	_, err := newOutput(&bytes.Buffer{}, "xml")
+	assert.NotNil(t, err)
+}
+
+func TestOutput_Truncate(t *testing.T) {
+	table, _ := newOutput(&bytes.Buffer{}, "table")
+	csv, _ := newOutput(&bytes.Buffer{}, "csv")
+	assert.Equal(t, "abcdefg...", table.truncate("abcdefghijklmn", 10))
+	assert.Equal(t, "first", table.truncate("first\nsecond", 10))
+	assert.Equal(t, "abcdefghijklmn", csv.truncate("abcdefghijklmn", 10))
+}
@@ -0,0 +1,262 @@
+package icinga2
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// ConflictSuffix renames colliding host to host_server
+	ConflictSuffix = "suffix"
+	// ConflictPrefix renames colliding host to server_host
+	ConflictPrefix = "prefix"
+)
+
+// ProxyConfig is the on-disk configuration of the Proxy
+type ProxyConfig struct {
+	Servers map[string]Icinga2ServerConfig `yaml:"servers" json:"servers"`
+	// how to rename hosts existing on more than one server, "suffix" (default) or "prefix"
+	ConflictStrategy string `yaml:"conflict_strategy" json:"conflict_strategy"`
+	// query node name of every server on (re)load and reject config with two servers pointing at the same node,
+	// as their objects would be merged as conflicts
+	RejectDuplicateNodes bool `yaml:"reject_duplicate_nodes" json:"reject_duplicate_nodes"`
+}
+
+// Duration is time.Duration that can be decoded from strings like "30s" in both YAML and JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration should be a string like \"30s\": %s", err)
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) UnmarshalYAML(v *yaml.Node) error {
+	return d.parse(v.Value)
+}
+
+func (d *Duration) parse(s string) error {
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+// LoadProxyConfig loads proxy config from YAML or JSON (by .json extension) file, resolves credential references and validates it.
+//
+// User and Pass can be given as "env:VARIABLE" to read them from environment or as "file:/path" to read them from file
+func LoadProxyConfig(path string) (*ProxyConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %s", err)
+	}
+	var cfg ProxyConfig
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config %s: %s", path, err)
+	}
+	for name, s := range cfg.Servers {
+		s.User, err = resolveCredential(s.User)
+		if err != nil {
+			return nil, fmt.Errorf("server %s: user: %s", name, err)
+		}
+		s.Pass, err = resolveCredential(s.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("server %s: pass: %s", name, err)
+		}
+		cfg.Servers[name] = s
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("error in config %s: %s", path, err)
+	}
+	return &cfg, nil
+}
+
+func resolveCredential(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "env:"):
+		v, ok := os.LookupEnv(strings.TrimPrefix(s, "env:"))
+		if !ok {
+			return "", fmt.Errorf("environment variable %s not set", strings.TrimPrefix(s, "env:"))
+		}
+		return v, nil
+	case strings.HasPrefix(s, "file:"):
+		v, err := ioutil.ReadFile(strings.TrimPrefix(s, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(v), "\r\n"), nil
+	}
+	return s, nil
+}
+
+// Validate checks whether config is usable
+func (c *ProxyConfig) Validate() error {
+	if len(c.Servers) == 0 {
+		return fmt.Errorf("no servers configured")
+	}
+	for name, s := range c.Servers {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("server %s: %s", name, err)
+		}
+	}
+	switch c.ConflictStrategy {
+	case "", ConflictSuffix, ConflictPrefix:
+	default:
+		return fmt.Errorf("unknown conflict_strategy [%s], should be one of %s, %s", c.ConflictStrategy, ConflictSuffix, ConflictPrefix)
+	}
+	return nil
+}
+
+func (s *Icinga2ServerConfig) Validate() error {
+	if s.ServerURL == "" {
+		return fmt.Errorf("server_url is empty")
+	}
+	u, err := url.Parse(s.ServerURL)
+	if err != nil {
+		return fmt.Errorf("error parsing server_url: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("server_url [%s] should be http:// or https://", s.ServerURL)
+	}
+	if (s.ClientCert == "") != (s.ClientKey == "") {
+		return fmt.Errorf("both client_cert and client_key have to be set")
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout can't be negative")
+	}
+	return nil
+}
+
+func (s *Icinga2ServerConfig) httpClient() (*http.Client, error) {
+	c := httpClient()
+	if s.Timeout > 0 {
+		c.Timeout = time.Duration(s.Timeout)
+	}
+	if s.CACert == "" && s.ClientCert == "" && !s.InsecureSkipVerify {
+		return c, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: s.InsecureSkipVerify}
+	if s.CACert != "" {
+		pem, err := ioutil.ReadFile(s.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca_cert: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", s.CACert)
+		}
+	}
+	if s.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(s.ClientCert, s.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.Transport = transport
+	return c, nil
+}
+
+// NewFromConfig creates API client using server config, including TLS and timeout settings
+func NewFromConfig(cfg Icinga2ServerConfig) (*API, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	a, err := New(cfg.ServerURL, cfg.User, cfg.Pass)
+	if err != nil {
+		return nil, err
+	}
+	a.httpClient, err = cfg.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func conflictResolverFor(strategy string) func(icinga2ServerName string, hostName string) (newName string) {
+	if strategy == ConflictPrefix {
+		return func(icinga2ServerName string, hostName string) (newName string) {
+			return fmt.Sprintf("%s_%s", icinga2ServerName, hostName)
+		}
+	}
+	return func(icinga2ServerName string, hostName string) (newName string) {
+		return fmt.Sprintf("%s_%s", hostName, icinga2ServerName)
+	}
+}
+
+// WatchConfig reloads proxy from config file on SIGHUP or when file modification time changes (checked every pollInterval,
+// pollInterval <= 0 disables polling). Invalid config is logged and ignored, proxy keeps running with old one. Call returned function to stop watching
+func (a *Proxy) WatchConfig(path string, pollInterval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	var lastMod time.Time
+	if fi, err := os.Stat(path); err == nil {
+		lastMod = fi.ModTime()
+	}
+	reload := func() {
+		cfg, err := LoadProxyConfig(path)
+		if err == nil {
+			err = a.Reload(cfg)
+		}
+		if err != nil {
+			log.Printf("error reloading config %s: %s", path, err)
+		}
+	}
+	go func() {
+		// nil channel never fires so without polling only SIGHUP reloads
+		var tick <-chan time.Time
+		if pollInterval > 0 {
+			t := time.NewTicker(pollInterval)
+			defer t.Stop()
+			tick = t.C
+		}
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-done:
+				return
+			case <-sig:
+				reload()
+			case <-tick:
+				fi, err := os.Stat(path)
+				if err != nil || fi.ModTime().Equal(lastMod) {
+					continue
+				}
+				lastMod = fi.ModTime()
+				reload()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
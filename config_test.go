@@ -0,0 +1,127 @@
+package icinga2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLoadProxyConfig_YAML(t *testing.T) {
+	os.Setenv("TEST_ICINGA2_PASS", "secret1")
+	defer os.Unsetenv("TEST_ICINGA2_PASS")
+	cfg, err := LoadProxyConfig("testdata/proxy.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, ConflictPrefix, cfg.ConflictStrategy)
+	assert.Len(t, cfg.Servers, 2)
+	assert.Equal(t, "secret1", cfg.Servers["dc1"].Pass, "env reference")
+	assert.Equal(t, "secret2", cfg.Servers["dc2"].Pass, "file reference")
+	assert.Equal(t, 10*time.Second, time.Duration(cfg.Servers["dc1"].Timeout))
+	assert.True(t, cfg.Servers["dc1"].InsecureSkipVerify)
+	assert.Equal(t, map[string]string{"site": "dc1", "env": "prod"}, cfg.Servers["dc1"].Labels)
+	p, err := NewProxyFromConfig(cfg)
+	require.Nil(t, err)
+	assert.Equal(t, "dc1_host", p.resolveConflict("dc1", "host"))
+	assert.Equal(t, 10*time.Second, p.getServers()["dc1"].httpClient.Timeout)
+}
+
+func TestLoadProxyConfig_JSON(t *testing.T) {
+	cfg, err := LoadProxyConfig("testdata/proxy.json")
+	require.Nil(t, err)
+	assert.Equal(t, "plain", cfg.Servers["dc1"].Pass)
+	assert.Equal(t, 5*time.Second, time.Duration(cfg.Servers["dc1"].Timeout))
+}
+
+func TestLoadProxyConfig_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "icinga2-config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	os.Unsetenv("TEST_ICINGA2_MISSING")
+	tests := map[string]string{
+		"no servers":       "servers: {}",
+		"empty url":        "servers: {dc1: {user: a}}",
+		"bad scheme":       "servers: {dc1: {server_url: 'ftp://example.com'}}",
+		"unknown field":    "servers: {dc1: {server_url: 'https://example.com', password: a}}",
+		"missing env":      "servers: {dc1: {server_url: 'https://example.com', pass: 'env:TEST_ICINGA2_MISSING'}}",
+		"bad strategy":     "conflict_strategy: random\nservers: {dc1: {server_url: 'https://example.com'}}",
+		"bad timeout":      "servers: {dc1: {server_url: 'https://example.com', timeout: forever}}",
+		"cert without key": "servers: {dc1: {server_url: 'https://example.com', client_cert: /tmp/cert.pem}}",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "cfg.yaml")
+			require.Nil(t, ioutil.WriteFile(path, []byte(data), 0600))
+			_, err := LoadProxyConfig(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProxy_Reload(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
+	ts2 := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts_dedup.json")
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	hosts, err := p.GetHosts()
+	assert.Nil(t, err)
+	assert.Len(t, hosts, 7)
+	err = p.Reload(&ProxyConfig{
+		Servers: map[string]Icinga2ServerConfig{
+			"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+			"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+		},
+		ConflictStrategy: ConflictPrefix,
+	})
+	require.Nil(t, err)
+	hosts, err = p.GetHosts()
+	assert.Nil(t, err)
+	assert.Len(t, hosts, 14)
+	names := make(map[string]bool)
+	for _, h := range hosts {
+		names[h.Host] = true
+	}
+	assert.True(t, names["s1_t1-host1"], "prefix strategy after reload")
+	err = p.Reload(&ProxyConfig{
+		Servers: map[string]Icinga2ServerConfig{"s1": {ServerURL: "bad"}},
+	})
+	assert.Error(t, err)
+	assert.Len(t, p.getServers(), 2, "failed reload should keep old servers")
+}
+
+func TestProxy_Reload_KeepsConflictResolver(t *testing.T) {
+	servers := map[string]Icinga2ServerConfig{"s1": {ServerURL: "https://example.com"}}
+	p, err := NewProxy(servers)
+	require.Nil(t, err)
+	assert.Equal(t, "host_s1", p.resolveConflict("s1", "host"), "default strategy")
+	p.SetConflictResolver(func(icinga2ServerName string, hostName string) string {
+		return icinga2ServerName + "/" + hostName
+	})
+	require.Nil(t, p.Reload(&ProxyConfig{Servers: servers}))
+	assert.Equal(t, "s1/host", p.resolveConflict("s1", "host"), "reload without strategy keeps resolver")
+	require.Nil(t, p.Reload(&ProxyConfig{Servers: servers, ConflictStrategy: ConflictPrefix}))
+	assert.Equal(t, "s1_host", p.resolveConflict("s1", "host"), "strategy from config replaces resolver")
+}
+
+func TestProxy_WatchConfig_SIGHUPOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "icinga2-config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cfg.yaml")
+	require.Nil(t, ioutil.WriteFile(path, []byte("servers: {s1: {server_url: 'https://example.com'}}"), 0600))
+	cfg, err := LoadProxyConfig(path)
+	require.Nil(t, err)
+	p, err := NewProxyFromConfig(cfg)
+	require.Nil(t, err)
+	stop := p.WatchConfig(path, 0)
+	defer stop()
+	require.Nil(t, ioutil.WriteFile(path, []byte("servers: {s1: {server_url: 'https://example.com'}, s2: {server_url: 'https://example.org'}}"), 0600))
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return len(p.Servers()) == 2 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,120 @@
+package icinga2
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ScriptError is error returned by Icinga2 DSL evaluation
+type ScriptError struct {
+	Message string
+	// true if expression is not finished, e.g. unclosed bracket
+	Incomplete bool
+	// location of the error, Path is "<console>" for executed scripts
+	Location SourceLocation
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Location.Path, int(e.Location.FirstLine), int(e.Location.FirstColumn), e.Message)
+}
+
+// ScriptResult is value of evaluated expression
+type ScriptResult struct {
+	Status string
+	// result as returned by API
+	Raw json.RawMessage
+}
+
+// Value returns result decoded into generic types (float64, string, bool, []interface{}, map[string]interface{} or nil)
+func (r *ScriptResult) Value() (v interface{}, err error) {
+	err = r.Decode(&v)
+	return v, err
+}
+
+// Decode decodes result into v
+func (r *ScriptResult) Decode(v interface{}) error {
+	if len(r.Raw) == 0 {
+		return json.Unmarshal([]byte("null"), v)
+	}
+	return json.Unmarshal(r.Raw, v)
+}
+
+type consoleRequest struct {
+	Command   string `json:"command"`
+	Session   string `json:"session,omitempty"`
+	Sandboxed bool   `json:"sandboxed"`
+}
+
+type consoleResponse struct {
+	Results []struct {
+		Code                 float64         `json:"code"`
+		Status               string          `json:"status"`
+		Result               json.RawMessage `json:"result"`
+		IncompleteExpression bool            `json:"incomplete_expression"`
+		DebugInfo            SourceLocation  `json:"debug_info"`
+	} `json:"results"`
+	// set on request errors, e.g. missing permissions
+	Error  float64 `json:"error"`
+	Status string  `json:"status"`
+}
+
+// NewScriptSession returns random session id. Variables defined in script are kept between calls using the same session
+func NewScriptSession() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ExecuteScript evaluates Icinga2 DSL command. Evaluation errors are returned as *ScriptError.
+// Sandboxed mode disallows functions with side effects
+func (a *API) ExecuteScript(session string, command string, sandboxed bool) (r ScriptResult, err error) {
+	resp, err := a.console("execute-script", consoleRequest{Command: command, Session: session, Sandboxed: sandboxed})
+	if err != nil {
+		return r, err
+	}
+	r.Status = resp.Results[0].Status
+	r.Raw = resp.Results[0].Result
+	return r, nil
+}
+
+// AutoComplete returns suggestions for the last word of the command
+func (a *API) AutoComplete(session string, command string) (suggestions []string, err error) {
+	resp, err := a.console("auto-complete-script", consoleRequest{Command: command, Session: session, Sandboxed: true})
+	if err != nil {
+		return suggestions, err
+	}
+	suggestions = make([]string, 0)
+	if len(resp.Results[0].Result) == 0 {
+		return suggestions, nil
+	}
+	err = json.Unmarshal(resp.Results[0].Result, &suggestions)
+	if err != nil {
+		return suggestions, fmt.Errorf("error decoding suggestions: %s | %s", err, string(resp.Results[0].Result))
+	}
+	return suggestions, nil
+}
+
+func (a *API) console(action string, cmd consoleRequest) (resp consoleResponse, err error) {
+	body, err := a.request("POST", "/v1/console/"+action, cmd)
+	if err != nil {
+		return resp, err
+	}
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return resp, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+	}
+	if len(resp.Results) == 0 {
+		return resp, fmt.Errorf("console %s failed: [%d] %s", action, int(resp.Error), resp.Status)
+	}
+	res := resp.Results[0]
+	if res.Code >= 300 {
+		return resp, &ScriptError{
+			Message:    res.Status,
+			Incomplete: res.IncompleteExpression,
+			Location:   res.DebugInfo,
+		}
+	}
+	return resp, nil
+}
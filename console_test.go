@@ -0,0 +1,76 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_ExecuteScript(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/console/execute-script", "v1.console.execute-script.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	r, err := Api.ExecuteScript("s1", `get_host("t1-host1")`, true)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"command":"get_host(\"t1-host1\")","session":"s1","sandboxed":true}`, ts.LastBody())
+	assert.Equal(t, "Executed successfully.", r.Status)
+	var host struct {
+		Name   string   `json:"name"`
+		Groups []string `json:"groups"`
+	}
+	require.Nil(t, r.Decode(&host))
+	assert.Equal(t, "t1-host1", host.Name)
+	assert.Equal(t, []string{"debian-servers"}, host.Groups)
+	v, err := r.Value()
+	require.Nil(t, err)
+	assert.Equal(t, 0.0, v.(map[string]interface{})["state"])
+}
+
+func TestAPI_ExecuteScript_Error(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/console/execute-script", "v1.console.execute-script.error.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.ExecuteScript("", `nonexistent_host.name`, true)
+	require.NotNil(t, err)
+	scriptErr, ok := err.(*ScriptError)
+	require.True(t, ok, "should be *ScriptError")
+	assert.Equal(t, 1.0, scriptErr.Location.FirstLine)
+	assert.Equal(t, 23.0, scriptErr.Location.LastColumn)
+	assert.False(t, scriptErr.Incomplete)
+	assert.Contains(t, err.Error(), "<console>:1:1: Error: Tried to access undefined script variable")
+}
+
+func TestAPI_ExecuteScript_Forbidden(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/console/execute-script", "v1.console.forbidden.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.ExecuteScript("", `1 + 1`, false)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "[403] No permission")
+}
+
+func TestAPI_AutoComplete(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/console/auto-complete-script", "v1.console.auto-complete-script.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	s, err := Api.AutoComplete("s1", "get_ho")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"get_host", "get_host_group", "get_hosts"}, s)
+	var req map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(ts.LastBody()), &req))
+	assert.Equal(t, "get_ho", req["command"])
+}
+
+func TestNewScriptSession(t *testing.T) {
+	assert.Len(t, NewScriptSession(), 32)
+	assert.NotEqual(t, NewScriptSession(), NewScriptSession())
+}
@@ -0,0 +1,44 @@
+package icinga2
+
+import (
+	"encoding/json"
+)
+
+// Dependency is Icinga2 Dependency object, child host/service depends on parent host/service.
+// Service names are empty for host dependencies
+type Dependency struct {
+	Name                 string   `json:"name"`
+	ParentHostName       string   `json:"parent_host_name"`
+	ParentServiceName    string   `json:"parent_service_name"`
+	ChildHostName        string   `json:"child_host_name"`
+	ChildServiceName     string   `json:"child_service_name"`
+	DisableChecks        bool     `json:"disable_checks"`
+	DisableNotifications bool     `json:"disable_notifications"`
+	IgnoreSoftStates     bool     `json:"ignore_soft_states"`
+	Period               string   `json:"period"`
+	States               []string `json:"states"`
+}
+
+func (i *Icinga2APIResponse) GetDependencies() (v []Dependency) {
+	for _, obj := range i.Results {
+		if obj.Type != "Dependency" {
+			continue
+		}
+		var dep Dependency
+		err := json.Unmarshal(obj.Attrs, &dep)
+		if err != nil {
+			log.Printf("error unmarshalling dependency %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		v = append(v, dep)
+	}
+	return v
+}
+
+func (a *API) GetDependencies() (m []Dependency, err error) {
+	i, err := a.getObjects("Dependencies", QueryOptions{})
+	if err != nil {
+		return m, err
+	}
+	return i.GetDependencies(), nil
+}
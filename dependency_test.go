@@ -0,0 +1,23 @@
+package icinga2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_GetDependencies(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Dependencies", "v1.objects.dependencies.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	deps, err := Api.GetDependencies()
+	require.Nil(t, err)
+	require.Len(t, deps, 2)
+	assert.Equal(t, "t1-sw1", deps[0].ParentHostName)
+	assert.Equal(t, "t1-host1", deps[0].ChildHostName)
+	assert.Empty(t, deps[0].ChildServiceName)
+	assert.Equal(t, []string{"Up"}, deps[0].States)
+	assert.Equal(t, "NRPE", deps[1].ParentServiceName)
+	assert.Equal(t, "HTTPS example", deps[1].ChildServiceName)
+}
@@ -0,0 +1,121 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// DowntimeObject is downtime currently scheduled in Icinga2
+type DowntimeObject struct {
+	// set by Proxy
+	Server string `json:"server,omitempty"`
+	// full object name, used to remove the downtime
+	FullName    string    `json:"full_name"`
+	Name        string    `json:"name"`
+	HostName    string    `json:"host_name"`
+	ServiceName string    `json:"service_name"`
+	Author      string    `json:"author"`
+	Comment     string    `json:"comment"`
+	StartTime   Timestamp `json:"start_time"`
+	EndTime     Timestamp `json:"end_time"`
+	EntryTime   Timestamp `json:"entry_time"`
+	// zero until flexible downtime is triggered
+	TriggerTime Timestamp `json:"trigger_time"`
+	Fixed       bool      `json:"fixed"`
+	// duration of flexible downtime in seconds
+	Duration float64 `json:"duration"`
+	// name of scheduled downtime that created it, if any
+	ScheduledBy string  `json:"scheduled_by"`
+	LegacyID    float64 `json:"legacy_id"`
+}
+
+// Object returns host or "host!service" the downtime is set on
+func (d *DowntimeObject) Object() string {
+	if d.ServiceName == "" {
+		return d.HostName
+	}
+	return d.HostName + "!" + d.ServiceName
+}
+
+func (i *Icinga2APIResponse) GetDowntimes() (v []DowntimeObject) {
+	for _, obj := range i.Results {
+		if obj.Type != "Downtime" {
+			continue
+		}
+		var d DowntimeObject
+		err := json.Unmarshal(obj.Attrs, &d)
+		if err != nil {
+			log.Printf("error unmarshalling downtime %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		d.FullName = obj.Name
+		v = append(v, d)
+	}
+	return v
+}
+
+// GetDowntimes returns downtimes matching filter, all if filter is empty
+func (a *API) GetDowntimes(filter string) (m []DowntimeObject, err error) {
+	i, err := a.getObjects("Downtimes", QueryOptions{Filter: filter})
+	if err != nil {
+		return m, err
+	}
+	return i.GetDowntimes(), nil
+}
+
+// RemoveDowntimesByFilter removes downtimes matched by filter, e.g. `downtime.author == "ops"`
+func (a *API) RemoveDowntimesByFilter(filter string, opts ActionOptions) (ActionResults, error) {
+	return a.filterAction("remove-downtime", "Downtime", filter, nil, opts)
+}
+
+// GetDowntimes returns downtimes from all servers with Server set, ordered by server and name
+func (a *Proxy) GetDowntimes(filter string) (m []DowntimeObject, err error) {
+	var lock sync.Mutex
+	m = make([]DowntimeObject, 0)
+	errs := a.each(func(name string, s *API) error {
+		downtimes, err := s.GetDowntimes(filter)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, d := range downtimes {
+			d.Server = name
+			m = append(m, d)
+		}
+		return nil
+	})
+	sort.Slice(m, func(i, j int) bool {
+		if m[i].Server != m[j].Server {
+			return m[i].Server < m[j].Server
+		}
+		return m[i].FullName < m[j].FullName
+	})
+	return m, proxyError(errs)
+}
+
+// ScheduleDowntime schedules downtime on target on all servers, object limit applies to the total number of matched objects
+func (a *Proxy) ScheduleDowntime(target DowntimeTarget, downtime Downtime, opts ActionOptions) (ActionResults, error) {
+	err := downtime.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return a.proxyAction(target.Type, target.Filter, opts, func(s *API) (ActionResults, error) {
+		return s.ScheduleDowntime(target, downtime, ActionOptions{})
+	})
+}
+
+// RemoveDowntime removes downtime by name from every server that has it
+func (a *Proxy) RemoveDowntime(name string) (ActionResults, error) {
+	return a.proxyAction("Downtime", "", ActionOptions{}, func(s *API) (ActionResults, error) {
+		return s.RemoveDowntime(name)
+	})
+}
+
+// RemoveDowntimesByFilter removes downtimes matched by filter on all servers
+func (a *Proxy) RemoveDowntimesByFilter(filter string, opts ActionOptions) (ActionResults, error) {
+	return a.proxyAction("Downtime", filter, opts, func(s *API) (ActionResults, error) {
+		return s.RemoveDowntimesByFilter(filter, ActionOptions{})
+	})
+}
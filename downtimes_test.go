@@ -0,0 +1,66 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+)
+
+func TestAPI_GetDowntimes(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Downtimes", "v1.objects.downtimes.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	downtimes, err := Api.GetDowntimes("")
+	require.Nil(t, err)
+	require.Len(t, downtimes, 2)
+	assert.Equal(t, "t1-host1!478e55d3-9c2b-4a47-9e7c-3f1d4b9b2a10", downtimes[0].FullName)
+	assert.Equal(t, "t1-host1", downtimes[0].Object())
+	assert.Equal(t, int64(1625403600), downtimes[0].EndTime.Unix())
+	assert.True(t, downtimes[0].Fixed)
+	assert.Equal(t, "t1-host1!POSTGRES", downtimes[1].Object())
+	assert.True(t, downtimes[1].TriggerTime.IsZero())
+	assert.Equal(t, "t1-host1!POSTGRES!patch-window", downtimes[1].ScheduledBy)
+}
+
+func TestAPI_RemoveDowntimesByFilter(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/objects/Downtimes":       "v1.objects.downtimes.json",
+		"/v1/actions/remove-downtime": "v1.actions.remove-downtime.json",
+	})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.RemoveDowntimesByFilter(`downtime.author == "ops"`, ActionOptions{MaxObjects: 1})
+	assert.IsType(t, &TooManyObjectsError{}, err)
+	require.Len(t, ts.Requests(), 1)
+	u, _ := url.Parse(ts.Requests()[0].URI)
+	assert.Equal(t, "/v1/objects/Downtimes", u.Path)
+
+	_, err = Api.RemoveDowntimesByFilter(`downtime.author == "ops"`, ActionOptions{})
+	require.Nil(t, err)
+	require.Len(t, ts.Requests(), 2)
+	assert.Contains(t, ts.Requests()[1].Body, `"type":"Downtime"`)
+	assert.Contains(t, ts.Requests()[1].Body, `"filter":"downtime.author == \"ops\""`)
+}
+
+func TestProxy_GetDowntimes(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Downtimes", "v1.objects.downtimes.json")
+	defer ts.Close()
+	Api, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s2": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	downtimes, err := Api.GetDowntimes("")
+	require.Nil(t, err)
+	require.Len(t, downtimes, 4)
+	assert.Equal(t, "s1", downtimes[0].Server)
+	assert.Equal(t, "s1", downtimes[1].Server)
+	assert.Equal(t, "s2", downtimes[2].Server)
+}
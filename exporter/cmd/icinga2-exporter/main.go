@@ -0,0 +1,117 @@
+// icinga2-exporter exposes state of hosts and services of one or more Icinga2 servers as Prometheus metrics
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"github.com/efigence/go-icinga2/exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// varLabels is repeatable label=var.path flag
+type varLabels map[string]string
+
+func (v varLabels) String() string {
+	parts := make([]string, 0, len(v))
+	for k, path := range v {
+		parts = append(parts, k+"="+path)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (v varLabels) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("should be label=var.path, got [%s]", s)
+	}
+	v[parts[0]] = parts[1]
+	return nil
+}
+
+// regexpFlag is optional regexp, nil if not set
+type regexpFlag struct {
+	re *regexp.Regexp
+}
+
+func (r *regexpFlag) String() string {
+	if r.re == nil {
+		return ""
+	}
+	return r.re.String()
+}
+
+func (r *regexpFlag) Set(s string) (err error) {
+	r.re, err = regexp.Compile(s)
+	return err
+}
+
+func main() {
+	var server icinga2.Icinga2ServerConfig
+	cfg := exporter.Config{VarLabels: varLabels{}}
+	var include, exclude regexpFlag
+	configPath := flag.String("config", "", "proxy config file (YAML or JSON) with servers to export, reloaded on change or SIGHUP")
+	flag.StringVar(&server.ServerURL, "url", os.Getenv("ICINGA2_URL"), "API URL of single server, e.g. https://icinga:5665")
+	flag.StringVar(&server.User, "user", os.Getenv("ICINGA2_USER"), "API user")
+	flag.StringVar(&server.Pass, "pass", os.Getenv("ICINGA2_PASS"), "API password")
+	flag.StringVar(&server.CACert, "ca", "", "CA certificate used to verify server")
+	flag.BoolVar(&server.InsecureSkipVerify, "insecure", false, "don't verify server certificate")
+	listen := flag.String("listen", ":9665", "address to serve metrics on")
+	flag.StringVar(&cfg.HostFilter, "host-filter", "", "Icinga2 filter selecting exported hosts")
+	flag.StringVar(&cfg.ServiceFilter, "service-filter", "", "Icinga2 filter selecting exported services")
+	flag.BoolVar(&cfg.NoServices, "no-services", false, "only export host metrics")
+	flag.Var(varLabels(cfg.VarLabels), "var-label", "add label from custom variable, label=var.path, can be repeated")
+	flag.BoolVar(&cfg.Perfdata, "perfdata", false, "export performance data")
+	flag.Var(&include, "perfdata-include", "only export perfdata with label matching regexp")
+	flag.Var(&exclude, "perfdata-exclude", "don't export perfdata with label matching regexp")
+	flag.IntVar(&cfg.MaxPerfdataPerObject, "max-perfdata", 50, "maximum number of perfdata values per host or service, 0 means no limit")
+	flag.Parse()
+	cfg.PerfdataInclude = include.re
+	cfg.PerfdataExclude = exclude.re
+
+	var proxyCfg *icinga2.ProxyConfig
+	var err error
+	switch {
+	case *configPath != "":
+		proxyCfg, err = icinga2.LoadProxyConfig(*configPath)
+	case server.ServerURL != "":
+		proxyCfg = &icinga2.ProxyConfig{Servers: map[string]icinga2.Icinga2ServerConfig{"default": server}}
+		err = proxyCfg.Validate()
+	default:
+		err = fmt.Errorf("set -config or -url (or ICINGA2_URL)")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	proxy, err := icinga2.NewProxyFromConfig(proxyCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *configPath != "" {
+		proxy.WatchConfig(*configPath, time.Minute)
+	}
+	collector, err := exporter.NewProxyCollector(proxy, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector, collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><body><a href="/metrics">metrics</a></body></html>`)
+	})
+	log.Printf("listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
@@ -0,0 +1,313 @@
+// Package exporter exposes Icinga2 host and service state and performance data as Prometheus metrics
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"github.com/prometheus/client_golang/prometheus"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Source provides objects metrics are generated from, *icinga2.API implements it
+type Source interface {
+	QueryHosts(opts icinga2.QueryOptions) ([]icinga2.Host, error)
+	QueryServices(opts icinga2.QueryOptions) ([]icinga2.Service, error)
+}
+
+// Config selects exported objects and limits number of generated series
+type Config struct {
+	// Icinga2 filters limiting exported objects, all if empty
+	HostFilter    string
+	ServiceFilter string
+	// skip all service metrics
+	NoServices bool
+	// maps label name to custom variable path, e.g. {"env": "environment", "team": "owner.team"}.
+	// Variables of the object itself are used, missing ones give empty label
+	VarLabels map[string]string
+	// export parsed performance data as icinga2_perfdata_value
+	Perfdata bool
+	// only perfdata with label matching include (if set) and not matching exclude (if set) is exported
+	PerfdataInclude *regexp.Regexp
+	PerfdataExclude *regexp.Regexp
+	// maximum number of perfdata values exported per object, 0 means no limit. Dropped values are counted in icinga2_exporter_perfdata_dropped
+	MaxPerfdataPerObject int
+}
+
+var (
+	labelNameRe    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	reservedLabels = map[string]bool{"server": true, "host": true, "service": true, "metric": true, "unit": true}
+)
+
+// Validate checks var label names
+func (c *Config) Validate() error {
+	for name, path := range c.VarLabels {
+		if !labelNameRe.MatchString(name) {
+			return fmt.Errorf("invalid label name [%s]", name)
+		}
+		if reservedLabels[name] {
+			return fmt.Errorf("label name [%s] is reserved", name)
+		}
+		if path == "" {
+			return fmt.Errorf("label [%s] has no variable path", name)
+		}
+	}
+	if c.MaxPerfdataPerObject < 0 {
+		return fmt.Errorf("max perfdata per object can't be negative")
+	}
+	return nil
+}
+
+// checkableMetric is exported for both hosts and services. Value returns false if there is nothing to export
+type checkableMetric struct {
+	name  string
+	help  string
+	value func(c *icinga2.Checkable, now time.Time) (float64, bool)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var checkableMetrics = []checkableMetric{
+	{"state", "Icinga2 state, 0 is UP/OK", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		return c.State, true
+	}},
+	{"state_hard", "1 if state is hard", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		return boolValue(c.StateType == 1), true
+	}},
+	{"acknowledged", "1 if problem is acknowledged", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		return boolValue(c.Acknowledgement > 0), true
+	}},
+	{"downtime", "1 if object is in downtime", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		return boolValue(c.DowntimeDepth > 0), true
+	}},
+	{"flapping", "1 if object is flapping", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		return boolValue(c.Flapping), true
+	}},
+	{"last_check_age_seconds", "Time since the last check", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		if c.LastCheck.IsZero() {
+			return 0, false
+		}
+		return now.Sub(c.LastCheck.Time).Seconds(), true
+	}},
+	{"check_latency_seconds", "Latency of the last check", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		if c.LastCheckResult.ScheduleStart.IsZero() {
+			return 0, false
+		}
+		return c.LastCheckResult.Latency().Seconds(), true
+	}},
+	{"check_execution_seconds", "Execution time of the last check", func(c *icinga2.Checkable, now time.Time) (float64, bool) {
+		if c.LastCheckResult.ExecutionStart.IsZero() {
+			return 0, false
+		}
+		return c.LastCheckResult.ExecutionTime().Seconds(), true
+	}},
+}
+
+// Collector is prometheus.Collector querying Icinga2 servers on every scrape
+type Collector struct {
+	sources   func() map[string]Source
+	cfg       Config
+	varLabels []string
+	// now is used to compute check age
+	now func() time.Time
+
+	hostDescs    []*prometheus.Desc
+	serviceDescs []*prometheus.Desc
+	perfdataDesc *prometheus.Desc
+	upDesc       *prometheus.Desc
+	durationDesc *prometheus.Desc
+	droppedDesc  *prometheus.Desc
+}
+
+// NewCollector creates collector for fixed set of servers, keys are used as server label
+func NewCollector(sources map[string]Source, cfg Config) (*Collector, error) {
+	return newCollector(func() map[string]Source { return sources }, cfg)
+}
+
+// NewProxyCollector creates collector for servers of the proxy, config reloads are picked up on the next scrape
+func NewProxyCollector(p *icinga2.Proxy, cfg Config) (*Collector, error) {
+	return newCollector(func() map[string]Source {
+		servers := p.Servers()
+		sources := make(map[string]Source, len(servers))
+		for k, v := range servers {
+			sources[k] = v
+		}
+		return sources
+	}, cfg)
+}
+
+func newCollector(sources func() map[string]Source, cfg Config) (*Collector, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	c := &Collector{sources: sources, cfg: cfg, now: time.Now}
+	for name := range cfg.VarLabels {
+		c.varLabels = append(c.varLabels, name)
+	}
+	sort.Strings(c.varLabels)
+	hostLabels := append([]string{"server", "host"}, c.varLabels...)
+	serviceLabels := append([]string{"server", "host", "service"}, c.varLabels...)
+	for _, m := range checkableMetrics {
+		c.hostDescs = append(c.hostDescs, prometheus.NewDesc("icinga2_host_"+m.name, "Host: "+m.help, hostLabels, nil))
+		c.serviceDescs = append(c.serviceDescs, prometheus.NewDesc("icinga2_service_"+m.name, "Service: "+m.help, serviceLabels, nil))
+	}
+	c.perfdataDesc = prometheus.NewDesc("icinga2_perfdata_value", "Performance data value normalized to base unit, service is empty for host checks",
+		append([]string{"server", "host", "service", "metric", "unit"}, c.varLabels...), nil)
+	c.upDesc = prometheus.NewDesc("icinga2_up", "1 if server was queried successfully", []string{"server"}, nil)
+	c.durationDesc = prometheus.NewDesc("icinga2_scrape_duration_seconds", "Time spent querying server", []string{"server"}, nil)
+	c.droppedDesc = prometheus.NewDesc("icinga2_exporter_perfdata_dropped", "Perfdata values not exported because of per-object limit", []string{"server"}, nil)
+	return c, nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for i := range checkableMetrics {
+		ch <- c.hostDescs[i]
+		if !c.cfg.NoServices {
+			ch <- c.serviceDescs[i]
+		}
+	}
+	if c.cfg.Perfdata {
+		ch <- c.perfdataDesc
+		ch <- c.droppedDesc
+	}
+	ch <- c.upDesc
+	ch <- c.durationDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for name, src := range c.sources() {
+		wg.Add(1)
+		go func(name string, src Source) {
+			defer wg.Done()
+			c.collectServer(ch, name, src)
+		}(name, src)
+	}
+	wg.Wait()
+}
+
+func (c *Collector) queryOptions(filter string) icinga2.QueryOptions {
+	// monitoring attributes are always added, "name" stops API from returning all of them
+	attrs := []string{"name"}
+	if len(c.varLabels) > 0 {
+		attrs = append(attrs, "vars")
+	}
+	return icinga2.QueryOptions{Filter: filter, Attrs: attrs}
+}
+
+func (c *Collector) collectServer(ch chan<- prometheus.Metric, server string, src Source) {
+	start := time.Now()
+	up := 1.0
+	dropped := 0
+	hosts, err := src.QueryHosts(c.queryOptions(c.cfg.HostFilter))
+	if err != nil {
+		up = 0
+	}
+	var services []icinga2.Service
+	if err == nil && !c.cfg.NoServices {
+		services, err = src.QueryServices(c.queryOptions(c.cfg.ServiceFilter))
+		if err != nil {
+			up = 0
+		}
+	}
+	// partial data would make series disappear and come back, so nothing is exported if any query failed
+	if up == 1 {
+		now := c.now()
+		for i := range hosts {
+			h := &hosts[i]
+			labels := append([]string{server, h.Name}, c.varValues(h.Vars)...)
+			c.collectCheckable(ch, c.hostDescs, &h.Checkable, labels, now)
+			dropped += c.collectPerfdata(ch, &h.Checkable, server, h.Name, "")
+		}
+		for i := range services {
+			s := &services[i]
+			labels := append([]string{server, s.HostName, s.Name}, c.varValues(s.Vars)...)
+			c.collectCheckable(ch, c.serviceDescs, &s.Checkable, labels, now)
+			dropped += c.collectPerfdata(ch, &s.Checkable, server, s.HostName, s.Name)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.upDesc, prometheus.GaugeValue, up, server)
+	ch <- prometheus.MustNewConstMetric(c.durationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), server)
+	if c.cfg.Perfdata {
+		ch <- prometheus.MustNewConstMetric(c.droppedDesc, prometheus.GaugeValue, float64(dropped), server)
+	}
+}
+
+func (c *Collector) collectCheckable(ch chan<- prometheus.Metric, descs []*prometheus.Desc, obj *icinga2.Checkable, labels []string, now time.Time) {
+	for i, m := range checkableMetrics {
+		v, ok := m.value(obj, now)
+		if ok {
+			ch <- prometheus.MustNewConstMetric(descs[i], prometheus.GaugeValue, v, labels...)
+		}
+	}
+}
+
+// collectPerfdata exports perfdata of the object and returns number of values dropped by the limit
+func (c *Collector) collectPerfdata(ch chan<- prometheus.Metric, obj *icinga2.Checkable, server string, host string, service string) (dropped int) {
+	if !c.cfg.Perfdata {
+		return 0
+	}
+	// invalid values are skipped, everything parsed before them is exported
+	perfdata, _ := obj.LastCheckResult.Perfdata()
+	seen := make(map[string]bool, len(perfdata))
+	vars := c.varValues(obj.Vars)
+	for _, p := range perfdata {
+		if p.Undetermined || seen[p.Label] {
+			continue
+		}
+		if c.cfg.PerfdataInclude != nil && !c.cfg.PerfdataInclude.MatchString(p.Label) {
+			continue
+		}
+		if c.cfg.PerfdataExclude != nil && c.cfg.PerfdataExclude.MatchString(p.Label) {
+			continue
+		}
+		if c.cfg.MaxPerfdataPerObject > 0 && len(seen) >= c.cfg.MaxPerfdataPerObject {
+			dropped++
+			continue
+		}
+		seen[p.Label] = true
+		labels := append([]string{server, host, service, p.Label, p.Unit}, vars...)
+		ch <- prometheus.MustNewConstMetric(c.perfdataDesc, prometheus.GaugeValue, p.Value, labels...)
+	}
+	return dropped
+}
+
+// varValues returns values of var labels in label name order
+func (c *Collector) varValues(vars icinga2.Vars) []string {
+	out := make([]string, len(c.varLabels))
+	for i, name := range c.varLabels {
+		v, ok := vars.Get(c.cfg.VarLabels[name])
+		if ok {
+			out[i] = labelValue(v)
+		}
+	}
+	return out
+}
+
+func labelValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,168 @@
+package exporter
+
+import (
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	hosts    []icinga2.Host
+	services []icinga2.Service
+	err      error
+	opts     []icinga2.QueryOptions
+	// number of QueryServices calls
+	serviceQueries int
+}
+
+func (f *fakeSource) QueryHosts(opts icinga2.QueryOptions) ([]icinga2.Host, error) {
+	f.opts = append(f.opts, opts)
+	return f.hosts, f.err
+}
+
+func (f *fakeSource) QueryServices(opts icinga2.QueryOptions) ([]icinga2.Service, error) {
+	f.opts = append(f.opts, opts)
+	f.serviceQueries++
+	return f.services, f.err
+}
+
+var testNow = time.Unix(1625400000, 0)
+
+func ts(t time.Time) icinga2.Timestamp {
+	return icinga2.Timestamp{Time: t}
+}
+
+func testSource() *fakeSource {
+	host := icinga2.Host{Checkable: icinga2.Checkable{
+		Name:            "web1",
+		State:           1,
+		StateType:       1,
+		DowntimeDepth:   1,
+		LastCheck:       ts(testNow.Add(-30 * time.Second)),
+		Vars:            icinga2.Vars{"env": "prod", "owner": map[string]interface{}{"team": "web"}},
+		LastCheckResult: icinga2.CheckResult{PerformanceData: icinga2.RawPerfdata{"rta=0.5ms;100;500 pl=0%"}},
+	}}
+	service := icinga2.Service{HostName: "web1", Checkable: icinga2.Checkable{
+		Name:            "DISK",
+		State:           2,
+		Acknowledgement: 1,
+		Flapping:        true,
+		LastCheck:       ts(testNow.Add(-time.Minute)),
+		Vars:            icinga2.Vars{"env": "prod"},
+		LastCheckResult: icinga2.CheckResult{
+			PerformanceData: icinga2.RawPerfdata{"/=10GB;;;0;100 /var=2GB /tmp=U /=11GB"},
+			ScheduleStart:   ts(testNow.Add(-62 * time.Second)),
+			ExecutionStart:  ts(testNow.Add(-61 * time.Second)),
+			ExecutionEnd:    ts(testNow.Add(-59 * time.Second)),
+			ScheduleEnd:     ts(testNow.Add(-58 * time.Second)),
+		},
+	}}
+	return &fakeSource{hosts: []icinga2.Host{host}, services: []icinga2.Service{service}}
+}
+
+func newTestCollector(t *testing.T, sources map[string]Source, cfg Config) *Collector {
+	c, err := NewCollector(sources, cfg)
+	require.Nil(t, err)
+	c.now = func() time.Time { return testNow }
+	return c
+}
+
+func TestCollector_State(t *testing.T) {
+	src := testSource()
+	c := newTestCollector(t, map[string]Source{"s1": src}, Config{VarLabels: map[string]string{"env": "env", "team": "owner.team"}})
+	expected := `
+# HELP icinga2_host_state Host: Icinga2 state, 0 is UP/OK
+# TYPE icinga2_host_state gauge
+icinga2_host_state{env="prod",host="web1",server="s1",team="web"} 1
+# HELP icinga2_host_downtime Host: 1 if object is in downtime
+# TYPE icinga2_host_downtime gauge
+icinga2_host_downtime{env="prod",host="web1",server="s1",team="web"} 1
+# HELP icinga2_host_last_check_age_seconds Host: Time since the last check
+# TYPE icinga2_host_last_check_age_seconds gauge
+icinga2_host_last_check_age_seconds{env="prod",host="web1",server="s1",team="web"} 30
+# HELP icinga2_service_acknowledged Service: 1 if problem is acknowledged
+# TYPE icinga2_service_acknowledged gauge
+icinga2_service_acknowledged{env="prod",host="web1",server="s1",service="DISK",team=""} 1
+# HELP icinga2_service_check_execution_seconds Service: Execution time of the last check
+# TYPE icinga2_service_check_execution_seconds gauge
+icinga2_service_check_execution_seconds{env="prod",host="web1",server="s1",service="DISK",team=""} 2
+# HELP icinga2_service_check_latency_seconds Service: Latency of the last check
+# TYPE icinga2_service_check_latency_seconds gauge
+icinga2_service_check_latency_seconds{env="prod",host="web1",server="s1",service="DISK",team=""} 2
+# HELP icinga2_up 1 if server was queried successfully
+# TYPE icinga2_up gauge
+icinga2_up{server="s1"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"icinga2_host_state", "icinga2_host_downtime", "icinga2_host_last_check_age_seconds", "icinga2_service_acknowledged",
+		"icinga2_service_check_execution_seconds", "icinga2_service_check_latency_seconds", "icinga2_up")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, testutil.CollectAndCount(c, "icinga2_perfdata_value"), "perfdata is disabled by default")
+	assert.Equal(t, 0, testutil.CollectAndCount(c, "icinga2_host_check_latency_seconds"), "host has no schedule times")
+	require.NotEmpty(t, src.opts)
+	assert.Equal(t, []string{"name", "vars"}, src.opts[0].Attrs)
+}
+
+func TestCollector_Perfdata(t *testing.T) {
+	c := newTestCollector(t, map[string]Source{"s1": testSource()}, Config{
+		Perfdata:             true,
+		PerfdataExclude:      regexp.MustCompile(`^pl$`),
+		MaxPerfdataPerObject: 1,
+	})
+	expected := `
+# HELP icinga2_perfdata_value Performance data value normalized to base unit, service is empty for host checks
+# TYPE icinga2_perfdata_value gauge
+icinga2_perfdata_value{host="web1",metric="rta",server="s1",service="",unit="s"} 0.0005
+icinga2_perfdata_value{host="web1",metric="/",server="s1",service="DISK",unit="B"} 1e+10
+# HELP icinga2_exporter_perfdata_dropped Perfdata values not exported because of per-object limit
+# TYPE icinga2_exporter_perfdata_dropped gauge
+icinga2_exporter_perfdata_dropped{server="s1"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "icinga2_perfdata_value", "icinga2_exporter_perfdata_dropped"))
+}
+
+func TestCollector_Failed(t *testing.T) {
+	failed := testSource()
+	failed.err = fmt.Errorf("connection refused")
+	c := newTestCollector(t, map[string]Source{"s1": testSource(), "s2": failed}, Config{})
+	expected := `
+# HELP icinga2_up 1 if server was queried successfully
+# TYPE icinga2_up gauge
+icinga2_up{server="s1"} 1
+icinga2_up{server="s2"} 0
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "icinga2_up"))
+	assert.Equal(t, 1, testutil.CollectAndCount(c, "icinga2_host_state"))
+	assert.Equal(t, 1, testutil.CollectAndCount(c, "icinga2_service_state"))
+	assert.Equal(t, 0, failed.serviceQueries, "services are not queried after hosts failed")
+
+	c = newTestCollector(t, map[string]Source{"s1": testSource()}, Config{NoServices: true})
+	assert.Equal(t, 0, testutil.CollectAndCount(c, "icinga2_service_state"))
+}
+
+func TestCollector_Lint(t *testing.T) {
+	c := newTestCollector(t, map[string]Source{"s1": testSource()}, Config{Perfdata: true})
+	reg := prometheus.NewPedanticRegistry()
+	require.Nil(t, reg.Register(c))
+	_, err := reg.Gather()
+	assert.Nil(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, cfg := range []Config{
+		{VarLabels: map[string]string{"bad-name": "env"}},
+		{VarLabels: map[string]string{"host": "env"}},
+		{VarLabels: map[string]string{"env": ""}},
+		{MaxPerfdataPerObject: -1},
+	} {
+		_, err := NewCollector(nil, cfg)
+		assert.NotNil(t, err, "%+v", cfg)
+	}
+}
@@ -0,0 +1,157 @@
+package icinga2
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter is Icinga2 filter expression built from parts that take care of quoting.
+// It remembers attributes it refers to so they can be validated
+type Filter struct {
+	expr  string
+	attrs []string
+}
+
+// String returns filter expression
+func (f Filter) String() string {
+	return f.expr
+}
+
+// Attributes returns attributes used in the filter, like "host.name" or "service.vars.os"
+func (f Filter) Attributes() []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0, len(f.attrs))
+	for _, a := range f.attrs {
+		if !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// RawFilter wraps expression written by hand. Attributes used in it are not known
+func RawFilter(expr string) Filter {
+	return Filter{expr: expr}
+}
+
+// Eq matches objects whose attribute equals value
+func Eq(attr string, value interface{}) Filter {
+	return Filter{expr: attr + "==" + literal(value), attrs: []string{attr}}
+}
+
+// NotEq matches objects whose attribute is not equal to value
+func NotEq(attr string, value interface{}) Filter {
+	return Filter{expr: attr + "!=" + literal(value), attrs: []string{attr}}
+}
+
+// Match matches attribute against glob pattern (* and ?)
+func Match(pattern string, attr string) Filter {
+	return Filter{expr: "match(" + quoteString(pattern) + ", " + attr + ")", attrs: []string{attr}}
+}
+
+// Regex matches attribute against regular expression
+func Regex(pattern string, attr string) Filter {
+	return Filter{expr: "regex(" + quoteString(pattern) + ", " + attr + ")", attrs: []string{attr}}
+}
+
+// In matches objects whose array attribute (like host.groups) contains value
+func In(value interface{}, attr string) Filter {
+	return Filter{expr: literal(value) + " in " + attr, attrs: []string{attr}}
+}
+
+// And matches objects matching all filters. Without filters it matches nothing
+func And(filters ...Filter) Filter {
+	return join(" && ", filters)
+}
+
+// Or matches objects matching any of filters. Without filters it matches nothing
+func Or(filters ...Filter) Filter {
+	return join(" || ", filters)
+}
+
+// Not negates filter
+func Not(f Filter) Filter {
+	return Filter{expr: "!(" + f.expr + ")", attrs: f.attrs}
+}
+
+func join(op string, filters []Filter) Filter {
+	// empty expression would select all objects
+	if len(filters) == 0 {
+		return Filter{expr: "false"}
+	}
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	var out Filter
+	parts := make([]string, len(filters))
+	for i, f := range filters {
+		parts[i] = "(" + f.expr + ")"
+		out.attrs = append(out.attrs, f.attrs...)
+	}
+	out.expr = strings.Join(parts, op)
+	return out
+}
+
+var identifierRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// VarAttr returns reference to custom variable of object type ("host" or "service"). Nested path is separated by dots
+func VarAttr(objType string, path string) string {
+	out := objType + ".vars"
+	for _, part := range strings.Split(path, ".") {
+		if identifierRe.MatchString(part) {
+			out += "." + part
+		} else {
+			out += "[" + quoteString(part) + "]"
+		}
+	}
+	return out
+}
+
+// VarEq matches objects whose custom variable equals value
+func VarEq(objType string, path string, value interface{}) Filter {
+	return Eq(VarAttr(objType, path), value)
+}
+
+// VarHas matches objects whose custom variable equals value or, if it is an array, contains it
+func VarHas(objType string, path string, value interface{}) Filter {
+	attr := VarAttr(objType, path)
+	return Filter{
+		expr:  attr + "==" + literal(value) + " || (typeof(" + attr + ")==Array && " + literal(value) + " in " + attr + ")",
+		attrs: []string{attr},
+	}
+}
+
+// literal formats Go value as Icinga2 DSL literal. Numbers and bools are matched by kind so named types like states work too
+func literal(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quoteString(t)
+	case []string:
+		parts := make([]string, len(t))
+		for i, s := range t {
+			parts[i] = quoteString(s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits())
+	default:
+		return quoteString(fmt.Sprint(v))
+	}
+}
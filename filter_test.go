@@ -0,0 +1,47 @@
+package icinga2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type namedState uint8
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		expr   string
+		attrs  []string
+	}{
+		{Eq("host.name", "t1-host1"), `host.name=="t1-host1"`, []string{"host.name"}},
+		{NotEq("service.state", 0), `service.state!=0`, []string{"service.state"}},
+		{Eq("host.vars.enabled", true), `host.vars.enabled==true`, []string{"host.vars.enabled"}},
+		{Eq("host.vars.x", nil), `host.vars.x==null`, []string{"host.vars.x"}},
+		{Eq("host.name", `a"b\c`), `host.name=="a\"b\\c"`, []string{"host.name"}},
+		{Match("t1-*", "host.name"), `match("t1-*", host.name)`, []string{"host.name"}},
+		{Regex("^t[0-9]", "host.name"), `regex("^t[0-9]", host.name)`, []string{"host.name"}},
+		{In("debian-servers", "host.groups"), `"debian-servers" in host.groups`, []string{"host.groups"}},
+		{Eq("host.address", []string{"a", "b"}), `host.address==["a", "b"]`, []string{"host.address"}},
+		{Eq("service.state", uint8(2)), `service.state==2`, []string{"service.state"}},
+		{Eq("host.state", namedState(1)), `host.state==1`, []string{"host.state"}},
+		{Eq("service.last_check", int32(-5)), `service.last_check==-5`, []string{"service.last_check"}},
+		{Eq("service.last_check", uint64(1700000000)), `service.last_check==1700000000`, []string{"service.last_check"}},
+		{Eq("service.execution_time", float32(0.5)), `service.execution_time==0.5`, []string{"service.execution_time"}},
+		{
+			And(Eq("host.name", "a"), Or(Eq("service.state", 2), Eq("service.state", 3.5))),
+			`(host.name=="a") && ((service.state==2) || (service.state==3.5))`,
+			[]string{"host.name", "service.state"},
+		},
+		{Not(Eq("host.name", "a")), `!(host.name=="a")`, []string{"host.name"}},
+		{And(Eq("host.name", "a")), `host.name=="a"`, []string{"host.name"}},
+		{And(), `false`, []string{}},
+		{Or(), `false`, []string{}},
+		{RawFilter("host.state==1"), "host.state==1", []string{}},
+		{VarEq("host", "site", "dc1"), `host.vars.site=="dc1"`, []string{"host.vars.site"}},
+		{VarEq("service", "disks.root-fs.path", "/"), `service.vars.disks["root-fs"].path=="/"`, []string{`service.vars.disks["root-fs"].path`}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expr, tt.filter.String())
+		assert.Equal(t, tt.attrs, tt.filter.Attributes(), tt.expr)
+	}
+}
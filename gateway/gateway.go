@@ -0,0 +1,405 @@
+// Package gateway serves a subset of Icinga2 REST API backed by Proxy, so existing clients can query and act on
+// all servers through one endpoint. Objects are returned in Icinga2 format with the server they came from in meta
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// User is gateway user authenticated by HTTP basic auth
+type User struct {
+	Password string `yaml:"password" json:"password"`
+	// only allow object queries
+	ReadOnly bool `yaml:"read_only" json:"read_only"`
+}
+
+// Config of the gateway
+type Config struct {
+	// users allowed to access gateway, keyed by user name
+	Users map[string]User `yaml:"users" json:"users"`
+	// reject downtimes matching more objects on all servers together, 0 means no limit
+	MaxDowntimeObjects int `yaml:"max_downtime_objects" json:"max_downtime_objects"`
+	// optional, failures of single servers are logged there
+	Logger icinga2.Logger `yaml:"-" json:"-"`
+}
+
+// FailedServersHeader lists servers that failed to respond, their objects are missing from the response
+const FailedServersHeader = "X-Gateway-Failed-Servers"
+
+// Handler is http.Handler serving /v1/objects/hosts, /v1/objects/services and /v1/actions/schedule-downtime
+type Handler struct {
+	proxy *icinga2.Proxy
+	cfg   Config
+}
+
+// New creates gateway handler for proxy
+func New(p *icinga2.Proxy, cfg Config) (*Handler, error) {
+	if len(cfg.Users) == 0 {
+		return nil, fmt.Errorf("no users configured")
+	}
+	for name, u := range cfg.Users {
+		if u.Password == "" {
+			return nil, fmt.Errorf("user %s has no password", name)
+		}
+	}
+	if cfg.MaxDowntimeObjects < 0 {
+		return nil, fmt.Errorf("max downtime objects can't be negative")
+	}
+	return &Handler{proxy: p, cfg: cfg}, nil
+}
+
+// object is Icinga2 API object with server tag in meta
+type object struct {
+	Attrs json.RawMessage            `json:"attrs"`
+	Joins map[string]json.RawMessage `json:"joins"`
+	Meta  meta                       `json:"meta"`
+	Name  string                     `json:"name"`
+	Type  string                     `json:"type"`
+}
+
+type meta struct {
+	Server string            `json:"server"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// actionResult is Icinga2 action result with server tag
+type actionResult struct {
+	Code     float64 `json:"code"`
+	Name     string  `json:"name,omitempty"`
+	Status   string  `json:"status"`
+	LegacyID int     `json:"legacy_id,omitempty"`
+	Server   string  `json:"server"`
+}
+
+// queryRequest is body of POST query with X-HTTP-Method-Override: GET
+type queryRequest struct {
+	Filter     string                 `json:"filter"`
+	FilterVars map[string]interface{} `json:"filter_vars"`
+	Attrs      []string               `json:"attrs"`
+	Joins      []string               `json:"joins"`
+}
+
+// objectTypes maps lower case URL type to Icinga2 type and attribute prefix used in filters
+var objectTypes = map[string]struct {
+	plural string
+	prefix string
+}{
+	"hosts":    {"Hosts", "host"},
+	"services": {"Services", "service"},
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Icinga 2"`)
+		writeError(w, http.StatusUnauthorized, "Unauthorized. Please check your user credentials.")
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 3 && len(parts) <= 4 && parts[0] == "v1" && parts[1] == "objects":
+		name := ""
+		if len(parts) == 4 {
+			name = parts[3]
+		}
+		h.serveObjects(w, r, strings.ToLower(parts[2]), name)
+	case len(parts) == 3 && parts[0] == "v1" && parts[1] == "actions" && parts[2] == "schedule-downtime":
+		if user.ReadOnly {
+			writeError(w, http.StatusForbidden, "No permission to execute actions.")
+			return
+		}
+		h.serveScheduleDowntime(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "The requested path '"+r.URL.Path+"' could not be found or the request method is not valid for this path.")
+	}
+}
+
+func (h *Handler) authenticate(r *http.Request) (User, bool) {
+	name, pass, ok := r.BasicAuth()
+	if !ok {
+		return User{}, false
+	}
+	user, ok := h.cfg.Users[name]
+	if !ok {
+		return User{}, false
+	}
+	return user, subtle.ConstantTimeCompare([]byte(pass), []byte(user.Password)) == 1
+}
+
+func (h *Handler) serveObjects(w http.ResponseWriter, r *http.Request, objType string, name string) {
+	t, ok := objectTypes[objType]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Invalid type '"+objType+"' specified.")
+		return
+	}
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); method == http.MethodPost && override != "" {
+		method = override
+	}
+	if method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Only GET queries are supported by the gateway.")
+		return
+	}
+	opts, err := queryOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if name != "" {
+		opts.Filter = andFilter(opts.Filter, nameFilter(t.prefix, name))
+	}
+	objects, status, err := h.queryObjects(w, t.plural, opts)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if name != "" && len(objects) == 0 {
+		writeError(w, http.StatusNotFound, "No objects found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": objects})
+}
+
+// queryOptions reads filter, attrs and joins from URL query and JSON body
+func queryOptions(r *http.Request) (opts icinga2.QueryOptions, err error) {
+	q := r.URL.Query()
+	if _, ok := q["filter_vars"]; ok {
+		return opts, fmt.Errorf("filter_vars are not supported by the gateway")
+	}
+	opts.Filter = q.Get("filter")
+	opts.Attrs = q["attrs"]
+	opts.Joins = q["joins"]
+	if r.Method != http.MethodPost {
+		return opts, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return opts, fmt.Errorf("error reading body: %s", err)
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return opts, nil
+	}
+	var req queryRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return opts, fmt.Errorf("invalid request body: %s", err)
+	}
+	if len(req.FilterVars) > 0 {
+		return opts, fmt.Errorf("filter_vars are not supported by the gateway")
+	}
+	if req.Filter != "" {
+		opts.Filter = req.Filter
+	}
+	opts.Attrs = append(opts.Attrs, req.Attrs...)
+	opts.Joins = append(opts.Joins, req.Joins...)
+	return opts, nil
+}
+
+// nameFilter selects single object by full name, "host!service" for services
+func nameFilter(prefix string, name string) string {
+	if prefix == "service" {
+		if i := strings.Index(name, "!"); i >= 0 {
+			return andFilter(icinga2.Eq("host.name", name[:i]).String(), icinga2.Eq("service.name", name[i+1:]).String())
+		}
+	}
+	return icinga2.Eq(prefix+".name", name).String()
+}
+
+func andFilter(filters ...string) string {
+	f := make([]icinga2.Filter, 0, len(filters))
+	for _, s := range filters {
+		if s != "" {
+			f = append(f, icinga2.RawFilter(s))
+		}
+	}
+	// And without filters matches nothing, empty filter here means all objects
+	if len(f) == 0 {
+		return ""
+	}
+	return icinga2.And(f...).String()
+}
+
+// queryObjects queries all servers and returns objects ordered by server and name.
+// Failed servers are listed in FailedServersHeader, error is returned only if all of them failed
+func (h *Handler) queryObjects(w http.ResponseWriter, objType string, opts icinga2.QueryOptions) ([]object, int, error) {
+	// servers and their labels have to come from the same config in case of reload in between
+	p := h.proxy.WithLabels(nil)
+	labels := p.ServerLabels()
+	var lock sync.Mutex
+	objects := make([]object, 0)
+	errs := p.Each(func(server string, s *icinga2.API) error {
+		i, err := s.QueryObjects(objType, opts)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, obj := range i.Results {
+			joins := obj.Joins
+			if joins == nil {
+				joins = map[string]json.RawMessage{}
+			}
+			objects = append(objects, object{
+				Attrs: obj.Attrs,
+				Joins: joins,
+				Meta:  meta{Server: server, Labels: labels[server]},
+				Name:  obj.Name,
+				Type:  obj.Type,
+			})
+		}
+		return nil
+	})
+	status, err := h.serverErrors(w, errs)
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Meta.Server != objects[j].Meta.Server {
+			return objects[i].Meta.Server < objects[j].Meta.Server
+		}
+		return objects[i].Name < objects[j].Name
+	})
+	return objects, status, err
+}
+
+// downtimeTypes are object types downtime can be scheduled for
+var downtimeTypes = map[string]string{"host": "Host", "service": "Service"}
+
+func (h *Handler) serveScheduleDowntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Actions have to be sent with POST.")
+		return
+	}
+	var req map[string]interface{}
+	err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	objType, _ := req["type"].(string)
+	objType, ok := downtimeTypes[strings.ToLower(objType)]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Parameter 'type' has to be Host or Service.")
+		return
+	}
+	filter, _ := req["filter"].(string)
+	if filter == "" {
+		// Icinga2 would downtime all objects
+		writeError(w, http.StatusBadRequest, "Parameter 'filter' is required by the gateway.")
+		return
+	}
+	// Icinga2 expects type capitalized
+	req["type"] = objType
+	// preview and action have to run on the same servers in case of reload in between
+	p := h.proxy.WithLabels(nil)
+	if h.cfg.MaxDowntimeObjects > 0 {
+		if _, ok := req["filter_vars"]; ok {
+			writeError(w, http.StatusBadRequest, "filter_vars can't be used when object limit is set.")
+			return
+		}
+		err := p.CheckObjectLimit(objType, filter, h.cfg.MaxDowntimeObjects)
+		if _, ok := err.(*icinga2.TooManyObjectsError); ok {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+	}
+	var lock sync.Mutex
+	results := make([]actionResult, 0)
+	errs := p.Each(func(server string, s *icinga2.API) error {
+		res, err := s.ExecuteAction("schedule-downtime", req)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, r := range res {
+			results = append(results, actionResult{Code: float64(r.Code), Name: r.Name, Status: r.Status, LegacyID: r.LegacyID, Server: server})
+		}
+		return nil
+	})
+	// like in preview, server that has none of the objects answers 404 and it is not a failure
+	for server, err := range errs {
+		if apiErr, ok := err.(*icinga2.APIError); ok && apiErr.Code == http.StatusNotFound {
+			errs[server] = nil
+		}
+	}
+	status, err := h.serverErrors(w, errs)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if len(results) == 0 {
+		writeError(w, http.StatusNotFound, "No objects found.")
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Server < results[j].Server })
+	// like Icinga2, failure of any object fails the request
+	status = http.StatusOK
+	for _, r := range results {
+		if r.Code >= 300 {
+			status = http.StatusInternalServerError
+		}
+	}
+	writeJSON(w, status, map[string]interface{}{"results": results})
+}
+
+// serverErrors sets FailedServersHeader and returns error with HTTP status if all servers failed.
+// Icinga2 errors are passed through if all servers returned the same code, e.g. 400 for invalid filter
+func (h *Handler) serverErrors(w http.ResponseWriter, errs map[string]error) (int, error) {
+	failed := make([]string, 0)
+	for server, err := range errs {
+		if err != nil {
+			failed = append(failed, server)
+			if h.cfg.Logger != nil {
+				h.cfg.Logger.Printf("gateway: server %s failed: %s", server, err)
+			}
+		}
+	}
+	sort.Strings(failed)
+	if len(failed) > 0 {
+		w.Header().Set(FailedServersHeader, strings.Join(failed, ","))
+	}
+	if len(failed) == 0 || len(failed) < len(errs) {
+		return 0, nil
+	}
+	var first *icinga2.APIError
+	for _, server := range failed {
+		apiErr, ok := errs[server].(*icinga2.APIError)
+		if !ok || (first != nil && apiErr.Code != first.Code) {
+			first = nil
+			break
+		}
+		if first == nil {
+			first = apiErr
+		}
+	}
+	if first != nil {
+		return first.Code, fmt.Errorf("%s", first.Status)
+	}
+	msgs := make([]string, len(failed))
+	for i, server := range failed {
+		msgs[i] = server + ": " + errs[server].Error()
+	}
+	return http.StatusBadGateway, fmt.Errorf("all servers failed: %s", strings.Join(msgs, "; "))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes error in Icinga2 format
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]interface{}{"error": status, "status": msg})
+}
@@ -0,0 +1,263 @@
+package gateway
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-icinga2"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newIcingaServer serves library testdata, paths without file get "No objects found." like from Icinga2
+func newIcingaServer(t *testing.T, files map[string]string) *icingatest.Server {
+	routes := map[string]string{"*": "404 error.no-objects-found.json"}
+	for path, file := range files {
+		routes[path] = file
+	}
+	return icingatest.NewServer(t, "../testdata", routes)
+}
+
+var testUsers = map[string]User{
+	"web": {Password: "webpass"},
+	"ro":  {Password: "ropass", ReadOnly: true},
+}
+
+func newGateway(t *testing.T, cfg Config, servers map[string]*icingatest.Server) *httptest.Server {
+	configs := make(map[string]icinga2.Icinga2ServerConfig)
+	for name, s := range servers {
+		configs[name] = icinga2.Icinga2ServerConfig{ServerURL: s.URL, User: "root", Pass: "icinga", Labels: map[string]string{"site": name}}
+	}
+	p, err := icinga2.NewProxy(configs)
+	require.Nil(t, err)
+	if cfg.Users == nil {
+		cfg.Users = testUsers
+	}
+	h, err := New(p, cfg)
+	require.Nil(t, err)
+	return httptest.NewServer(h)
+}
+
+type response struct {
+	Results []struct {
+		Attrs  map[string]interface{} `json:"attrs"`
+		Meta   meta                   `json:"meta"`
+		Name   string                 `json:"name"`
+		Type   string                 `json:"type"`
+		Code   float64                `json:"code"`
+		Server string                 `json:"server"`
+	} `json:"results"`
+	Error  float64 `json:"error"`
+	Status string  `json:"status"`
+}
+
+func do(t *testing.T, method string, u string, user string, pass string, body string, header map[string]string) (*http.Response, response) {
+	req, err := http.NewRequest(method, u, strings.NewReader(body))
+	require.Nil(t, err)
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	var r response
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&r))
+	return resp, r
+}
+
+func TestHandler_Hosts(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{"/v1/objects/Hosts": "v1.objects.hosts.json"})
+	defer s1.Close()
+	s2 := newIcingaServer(t, map[string]string{"/v1/objects/Hosts": "v1.objects.hosts_dedup.json"})
+	defer s2.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1, "s2": s2})
+	defer gw.Close()
+
+	resp, r := do(t, "GET", gw.URL+"/v1/objects/hosts?filter=true&attrs=address", "web", "webpass", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, r.Results, 14)
+	assert.Equal(t, "s1", r.Results[0].Meta.Server)
+	assert.Equal(t, "s1", r.Results[0].Meta.Labels["site"])
+	assert.Equal(t, "s2", r.Results[13].Meta.Server)
+	assert.Equal(t, "Host", r.Results[0].Type)
+	assert.True(t, r.Results[0].Name < r.Results[1].Name, "ordered by name within server")
+	assert.Empty(t, resp.Header.Get(FailedServersHeader))
+	require.Len(t, s1.Requests(), 1)
+	assert.Equal(t, "true", s1.Requests()[0].Query().Get("filter"))
+	assert.Equal(t, []string{"address"}, s1.Requests()[0].Query()["attrs"])
+}
+
+func TestHandler_Services_MethodOverride(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{"/v1/objects/Services": "v1.objects.services.json"})
+	defer s1.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1})
+	defer gw.Close()
+
+	resp, r := do(t, "POST", gw.URL+"/v1/objects/services/t1-host1!HTTP", "ro", "ropass",
+		`{"attrs": ["state"], "joins": ["host.address"]}`, map[string]string{"X-HTTP-Method-Override": "GET"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, r.Results)
+	require.Len(t, s1.Requests(), 1)
+	assert.Equal(t, `(host.name=="t1-host1") && (service.name=="HTTP")`, s1.Requests()[0].Query().Get("filter"))
+	assert.Equal(t, []string{"state"}, s1.Requests()[0].Query()["attrs"])
+	assert.Equal(t, []string{"host.address"}, s1.Requests()[0].Query()["joins"])
+
+	resp, r = do(t, "POST", gw.URL+"/v1/objects/services", "ro", "ropass", `{"filter_vars": {"x": 1}}`, map[string]string{"X-HTTP-Method-Override": "GET"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, float64(400), r.Error)
+	resp, _ = do(t, "DELETE", gw.URL+"/v1/objects/services/t1-host1!HTTP", "web", "webpass", "", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHandler_PartialFailure(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{"/v1/objects/Hosts": "v1.objects.hosts.json"})
+	defer s1.Close()
+	s2 := newIcingaServer(t, map[string]string{"/v1/objects/Hosts": "v1.objects.hosts.json"})
+	s2.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1, "s2": s2})
+	defer gw.Close()
+
+	resp, r := do(t, "GET", gw.URL+"/v1/objects/hosts", "web", "webpass", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, r.Results, 7)
+	assert.Equal(t, "s2", resp.Header.Get(FailedServersHeader))
+}
+
+func TestHandler_AllFailed(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{})
+	defer s1.Close()
+	s2 := newIcingaServer(t, map[string]string{})
+	defer s2.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1, "s2": s2})
+	defer gw.Close()
+
+	resp, r := do(t, "GET", gw.URL+"/v1/objects/hosts", "web", "webpass", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "same Icinga2 error on all servers is passed through")
+	assert.Equal(t, "No objects found.", r.Status)
+	assert.Equal(t, "s1,s2", resp.Header.Get(FailedServersHeader))
+
+	s2.Close()
+	resp, r = do(t, "GET", gw.URL+"/v1/objects/hosts", "web", "webpass", "", nil)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Contains(t, r.Status, "all servers failed")
+}
+
+func TestHandler_Auth(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{"/v1/objects/Hosts": "v1.objects.hosts.json"})
+	defer s1.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1})
+	defer gw.Close()
+
+	for _, creds := range [][2]string{{"", ""}, {"web", "bad"}, {"nobody", "webpass"}} {
+		resp, r := do(t, "GET", gw.URL+"/v1/objects/hosts", creds[0], creds[1], "", nil)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, creds[0])
+		assert.Equal(t, float64(401), r.Error)
+	}
+	resp, _ := do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "ro", "ropass", `{"type": "Host", "filter": "true"}`, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = do(t, "GET", gw.URL+"/v1/objects/zones", "web", "webpass", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, s1.Requests(), "nothing is forwarded")
+
+	_, err := New(nil, Config{})
+	assert.NotNil(t, err)
+	_, err = New(nil, Config{Users: map[string]User{"x": {}}})
+	assert.NotNil(t, err)
+}
+
+func TestHandler_ScheduleDowntime(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{
+		"/v1/objects/Hosts":             "v1.objects.hosts.json",
+		"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json",
+	})
+	defer s1.Close()
+	// no matching hosts on s2
+	s2 := newIcingaServer(t, map[string]string{})
+	defer s2.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1, "s2": s2})
+	defer gw.Close()
+
+	body := `{"type": "Host", "filter": "match(\"t1-mon*\", host.name)", "start_time": 1625400000, "end_time": 1625403600,
+		"author": "web", "comment": "upgrade", "child_options": "DowntimeTriggeredChildren"}`
+	resp, r := do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", body, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, r.Results, 2)
+	assert.Equal(t, "s1", r.Results[0].Server)
+	assert.Equal(t, float64(200), r.Results[0].Code)
+	assert.Empty(t, resp.Header.Get(FailedServersHeader), "no objects on a server is not a failure")
+	require.Len(t, s1.Requests(), 1)
+	assert.Contains(t, s1.Requests()[0].Body, `"child_options":"DowntimeTriggeredChildren"`, "request is passed as-is")
+
+	empty := newGateway(t, Config{}, map[string]*icingatest.Server{"s2": s2})
+	defer empty.Close()
+	resp, r = do(t, "POST", empty.URL+"/v1/actions/schedule-downtime", "web", "webpass", body, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "no objects on any server")
+	assert.Equal(t, "No objects found.", r.Status)
+	assert.Empty(t, resp.Header.Get(FailedServersHeader))
+
+	resp, _ = do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", `{"type": "Host"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "filter is required")
+	resp, _ = do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", `{"type": "Zone", "filter": "true"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do(t, "GET", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", "", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHandler_ScheduleDowntime_Limit(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{
+		"/v1/objects/Hosts":             "v1.objects.hosts.json",
+		"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json",
+	})
+	defer s1.Close()
+	gw := newGateway(t, Config{MaxDowntimeObjects: 5}, map[string]*icingatest.Server{"s1": s1})
+	defer gw.Close()
+
+	resp, r := do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", `{"type": "Host", "filter": "true"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "filter [true] matches 7 objects, limit is 5", r.Status)
+	require.Len(t, s1.Requests(), 1, "only preview query was sent")
+}
+
+func TestHandler_ScheduleDowntime_Type(t *testing.T) {
+	s1 := newIcingaServer(t, map[string]string{"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json"})
+	defer s1.Close()
+	gw := newGateway(t, Config{}, map[string]*icingatest.Server{"s1": s1})
+	defer gw.Close()
+
+	resp, _ := do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", `{"type": "host", "filter": "true"}`, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, s1.Requests(), 1)
+	assert.Contains(t, s1.Requests()[0].Body, `"type":"Host"`)
+}
+
+func TestHandler_ScheduleDowntime_PreviewFailed(t *testing.T) {
+	files := map[string]string{
+		"/v1/objects/Hosts":             "v1.objects.hosts.json",
+		"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json",
+	}
+	s1 := newIcingaServer(t, files)
+	defer s1.Close()
+	s2 := newIcingaServer(t, map[string]string{"/v1/objects/Hosts": "500 error.internal.json"})
+	defer s2.Close()
+	// no matching hosts on s3
+	s3 := newIcingaServer(t, map[string]string{})
+	defer s3.Close()
+
+	gw := newGateway(t, Config{MaxDowntimeObjects: 10}, map[string]*icingatest.Server{"s1": s1, "s2": s2})
+	defer gw.Close()
+	resp, r := do(t, "POST", gw.URL+"/v1/actions/schedule-downtime", "web", "webpass", `{"type": "Host", "filter": "true"}`, nil)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, "error previewing objects: failed on 1 of 2 servers: s2: [500] Error: Evaluation failed", r.Status)
+	require.Len(t, s1.Requests(), 1, "only preview query was sent")
+
+	gw2 := newGateway(t, Config{MaxDowntimeObjects: 10}, map[string]*icingatest.Server{"s1": s1, "s3": s3})
+	defer gw2.Close()
+	resp, _ = do(t, "POST", gw2.URL+"/v1/actions/schedule-downtime", "web", "webpass", `{"type": "Host", "filter": "true"}`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "server without matching objects is not a failure")
+}
@@ -1,8 +1,16 @@
 module github.com/efigence/go-icinga2
 
-go 1.14
+go 1.18
 
 require (
 	github.com/efigence/go-monitoring v0.0.3
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 )
@@ -0,0 +1,196 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-monitoring"
+	"sort"
+	"sync"
+)
+
+// Group is Icinga2 HostGroup or ServiceGroup
+type Group struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Notes       string `json:"notes"`
+	NotesURL    string `json:"notes_url"`
+	ActionURL   string `json:"action_url"`
+	Vars        Vars   `json:"vars"`
+}
+
+// GroupSummary is aggregated state of group members
+type GroupSummary struct {
+	Name  string `json:"name"`
+	Total int    `json:"total"`
+	// count of members by monitoring state
+	ByState map[uint8]int `json:"by_state"`
+	// worst state of any member, monitoring.HostInvalid/StatusInvalid for empty group
+	Worst uint8 `json:"worst"`
+	// members in problem state that are neither acknowledged nor in downtime
+	Unhandled int `json:"unhandled"`
+}
+
+// hostStateRank orders host states from the best to the worst
+var hostStateRank = map[uint8]int{
+	monitoring.HostUp:          1,
+	monitoring.HostUnreachable: 2,
+	monitoring.HostDown:        3,
+}
+
+// serviceStateRank orders service states from the best to the worst
+var serviceStateRank = map[uint8]int{
+	monitoring.StatusOk:       1,
+	monitoring.StatusWarning:  2,
+	monitoring.StatusUnknown:  3,
+	monitoring.StatusCritical: 4,
+}
+
+// SummarizeHosts aggregates state of hosts
+func SummarizeHosts(name string, hosts []monitoring.Host) GroupSummary {
+	s := GroupSummary{Name: name, ByState: make(map[uint8]int)}
+	for _, h := range hosts {
+		s.add(h.Common, monitoring.HostUp, hostStateRank)
+	}
+	return s
+}
+
+// SummarizeServices aggregates state of services
+func SummarizeServices(name string, services []monitoring.Service) GroupSummary {
+	s := GroupSummary{Name: name, ByState: make(map[uint8]int)}
+	for _, svc := range services {
+		s.add(svc.Common, monitoring.StatusOk, serviceStateRank)
+	}
+	return s
+}
+
+func (s *GroupSummary) add(c monitoring.Common, okState uint8, rank map[uint8]int) {
+	s.Total++
+	s.ByState[c.State]++
+	if s.Total == 1 || rank[c.State] > rank[s.Worst] {
+		s.Worst = c.State
+	}
+	if c.State != okState && !c.Acknowledged && !c.Downtime {
+		s.Unhandled++
+	}
+}
+
+func (i *Icinga2APIResponse) GetGroups() (v []Group) {
+	for _, obj := range i.Results {
+		if obj.Type != "HostGroup" && obj.Type != "ServiceGroup" {
+			continue
+		}
+		var group Group
+		err := json.Unmarshal(obj.Attrs, &group)
+		if err != nil {
+			log.Printf("error unmarshalling group %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		v = append(v, group)
+	}
+	return v
+}
+
+func (a *API) GetHostGroups() (m []Group, err error) {
+	i, err := a.getObjects("HostGroups", QueryOptions{})
+	if err != nil {
+		return m, err
+	}
+	return i.GetGroups(), nil
+}
+
+func (a *API) GetServiceGroups() (m []Group, err error) {
+	i, err := a.getObjects("ServiceGroups", QueryOptions{})
+	if err != nil {
+		return m, err
+	}
+	return i.GetGroups(), nil
+}
+
+func (a *API) GetHostsInGroup(group string) (m []monitoring.Host, err error) {
+	return a.GetHostsByFilter(In(group, "host.groups").String())
+}
+
+func (a *API) GetServicesInGroup(group string) (m []monitoring.Service, err error) {
+	return a.GetServicesByFilter(In(group, "service.groups").String())
+}
+
+func (a *API) HostGroupSummary(group string) (s GroupSummary, err error) {
+	hosts, err := a.GetHostsInGroup(group)
+	if err != nil {
+		return s, err
+	}
+	return SummarizeHosts(group, hosts), nil
+}
+
+func (a *API) ServiceGroupSummary(group string) (s GroupSummary, err error) {
+	services, err := a.GetServicesInGroup(group)
+	if err != nil {
+		return s, err
+	}
+	return SummarizeServices(group, services), nil
+}
+
+// GetHostGroups returns groups from all servers, groups with the same name are returned once
+func (a *Proxy) GetHostGroups() (m []Group, err error) {
+	return a.getGroups((*API).GetHostGroups)
+}
+
+// GetServiceGroups returns groups from all servers, groups with the same name are returned once
+func (a *Proxy) GetServiceGroups() (m []Group, err error) {
+	return a.getGroups((*API).GetServiceGroups)
+}
+
+// getGroups merges groups from all servers, group defined on more of them is taken from the first server by name
+func (a *Proxy) getGroups(get func(*API) ([]Group, error)) (m []Group, err error) {
+	var lock sync.Mutex
+	res := make(map[string][]Group)
+	errs := a.each(func(name string, s *API) error {
+		g, err := get(s)
+		lock.Lock()
+		defer lock.Unlock()
+		res[name] = g
+		return err
+	})
+	servers := make([]string, 0, len(res))
+	for k := range res {
+		servers = append(servers, k)
+	}
+	sort.Strings(servers)
+	groups := make(map[string]Group)
+	for _, server := range servers {
+		for _, group := range res[server] {
+			if _, ok := groups[group.Name]; !ok {
+				groups[group.Name] = group
+			}
+		}
+	}
+	m = make([]Group, 0, len(groups))
+	for _, g := range groups {
+		m = append(m, g)
+	}
+	sort.Slice(m, func(i, j int) bool { return m[i].Name < m[j].Name })
+	return m, proxyError(errs)
+}
+
+func (a *Proxy) GetHostsInGroup(group string) (m []monitoring.Host, err error) {
+	return a.GetHostsByFilter(In(group, "host.groups").String())
+}
+
+func (a *Proxy) GetServicesInGroup(group string) (m []monitoring.Service, err error) {
+	return a.GetServicesByFilter(In(group, "service.groups").String())
+}
+
+func (a *Proxy) HostGroupSummary(group string) (s GroupSummary, err error) {
+	hosts, err := a.GetHostsInGroup(group)
+	if err != nil {
+		return s, err
+	}
+	return SummarizeHosts(group, hosts), nil
+}
+
+func (a *Proxy) ServiceGroupSummary(group string) (s GroupSummary, err error) {
+	services, err := a.GetServicesInGroup(group)
+	if err != nil {
+		return s, err
+	}
+	return SummarizeServices(group, services), nil
+}
@@ -0,0 +1,115 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_GetHostGroups(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/HostGroups", "v1.objects.hostgroups.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	groups, err := Api.GetHostGroups()
+	require.Nil(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "debian-servers", groups[0].Name)
+	assert.Equal(t, "Debian servers", groups[0].DisplayName)
+	team, _ := groups[1].Vars.String("team")
+	assert.Equal(t, "ops", team)
+}
+
+func TestAPI_GetServiceGroups(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/ServiceGroups", "v1.objects.servicegroups.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	groups, err := Api.GetServiceGroups()
+	require.Nil(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "legacy", groups[0].Name)
+}
+
+func TestAPI_ServiceGroupSummary(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Services": "v1.objects.services.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	s, err := Api.ServiceGroupSummary("http")
+	require.Nil(t, err)
+	assert.Equal(t, `"http" in service.groups`, ts.LastQuery().Get("filter"))
+	assert.Equal(t, "http", s.Name)
+	assert.Equal(t, 7, s.Total)
+	assert.Equal(t, 7, s.ByState[monitoring.StatusOk])
+	assert.Equal(t, uint8(monitoring.StatusOk), s.Worst)
+	assert.Equal(t, 0, s.Unhandled)
+}
+
+func TestSummarizeServices(t *testing.T) {
+	svc := func(state uint8, acked bool, downtime bool) monitoring.Service {
+		var s monitoring.Service
+		s.State = state
+		s.Acknowledged = acked
+		s.Downtime = downtime
+		return s
+	}
+	s := SummarizeServices("g", []monitoring.Service{
+		svc(monitoring.StatusOk, false, false),
+		svc(monitoring.StatusUnknown, false, false),
+		svc(monitoring.StatusCritical, true, false),
+		svc(monitoring.StatusWarning, false, true),
+		svc(monitoring.StatusWarning, false, false),
+	})
+	assert.Equal(t, 5, s.Total)
+	assert.Equal(t, uint8(monitoring.StatusCritical), s.Worst)
+	assert.Equal(t, 2, s.ByState[monitoring.StatusWarning])
+	assert.Equal(t, 2, s.Unhandled)
+	assert.Equal(t, uint8(monitoring.StatusInvalid), SummarizeServices("empty", nil).Worst)
+}
+
+func TestSummarizeHosts(t *testing.T) {
+	host := func(state uint8) monitoring.Host {
+		var h monitoring.Host
+		h.State = state
+		return h
+	}
+	s := SummarizeHosts("g", []monitoring.Host{host(monitoring.HostUp), host(monitoring.HostDown), host(monitoring.HostUnreachable)})
+	assert.Equal(t, uint8(monitoring.HostDown), s.Worst)
+	assert.Equal(t, 2, s.Unhandled)
+}
+
+func TestProxy_GetHostGroups(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/HostGroups", "v1.objects.hostgroups.json")
+	ts2 := testServer(t, "/v1/objects/HostGroups", "v1.objects.hostgroups_dc2.json")
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		groups, err := p.GetHostGroups()
+		require.Nil(t, err)
+		require.Len(t, groups, 2, "deduplicated by name")
+		assert.Equal(t, "Debian servers", groups[0].DisplayName, "first server by name wins")
+	}
+}
+
+func TestProxy_HostGroupSummary(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
+	ts2 := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts_dedup.json")
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	s, err := p.HostGroupSummary("debian-servers")
+	require.Nil(t, err)
+	assert.Equal(t, 14, s.Total)
+	assert.Equal(t, uint8(monitoring.HostUp), s.Worst)
+}
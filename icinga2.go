@@ -5,18 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/efigence/go-monitoring"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
-var client = httpClient()
-
 type API struct {
-	URL  *url.URL
-	User string
-	Pass string
+	URL        *url.URL
+	User       string
+	Pass       string
+	httpClient *http.Client
+
+	hostStateMapper    HostStateMapper
+	serviceStateMapper ServiceStateMapper
+	// optional, used to validate attributes locally
+	schema *Schema
 }
 
 func New(apiURL, user, pass string) (ao *API, err error) {
@@ -27,6 +33,7 @@ func New(apiURL, user, pass string) (ao *API, err error) {
 	}
 	a.User = user
 	a.Pass = pass
+	a.httpClient = httpClient()
 	return &a, nil
 }
 
@@ -37,76 +44,135 @@ func httpClient() *http.Client {
 
 }
 
+// client returns configured HTTP client, or default one for API created without New
+func (a *API) client() *http.Client {
+	if a.httpClient == nil {
+		return httpClient()
+	}
+	return a.httpClient
+}
+
 func (a *API) GetHosts() (m []monitoring.Host, err error) {
 	return a.GetHostsByFilter("")
 }
 
 func (a *API) GetHostsByFilter(filter string) (m []monitoring.Host, err error) {
-	client := httpClient()
-	req, err := http.NewRequest("GET", a.URL.String()+"/v1/objects/Hosts", nil)
+	i, err := a.getObjects("Hosts", QueryOptions{Filter: filter})
 	if err != nil {
 		return m, err
 	}
-	req.Header.Set("Accept", "application/json")
-	if filter != "" {
-		q := req.URL.Query()
-		q.Add("filter", filter)
-		req.URL.RawQuery = q.Encode()
-	}
-	if len(a.User) > 0 {
-		req.SetBasicAuth(a.User, a.Pass)
-	}
-	resp, err := client.Do(req)
+	return i.GetHostsWith(a.hostState()), nil
+}
+
+func (a *API) GetServices() (m []monitoring.Service, err error) {
+	return a.GetServicesByFilter("")
+}
+
+func (a *API) GetServicesByFilter(filter string) (m []monitoring.Service, err error) {
+	i, err := a.getObjects("Services", QueryOptions{Filter: filter})
 	if err != nil {
 		return m, err
 	}
+	return i.GetServicesWith(a.serviceState()), nil
+}
+
+// getObjects queries /v1/objects/<objType> endpoint
+func (a *API) getObjects(objType string, opts QueryOptions) (i *Icinga2APIResponse, err error) {
+	resp, err := a.queryObjects(objType, opts)
+	if err != nil {
+		return i, err
+	}
 	defer resp.Body.Close()
-	var i Icinga2APIResponse
-	body, err := ioutil.ReadAll(resp.Body)
+	results, err := collectObjects(resp.Body)
+	// error status like "No objects found." means no results here
+	if _, ok := err.(*APIError); ok {
+		err = nil
+	}
 	if err != nil {
-		return m, err
+		return i, err
 	}
-	err = json.Unmarshal(body, &i)
+	return &Icinga2APIResponse{Results: results}, nil
+}
+
+// QueryObjects returns raw objects of type (plural, e.g. "Hosts") selected by options.
+// Unlike typed queries it returns *APIError when Icinga2 rejects the query, e.g. because of invalid filter
+func (a *API) QueryObjects(objType string, opts QueryOptions) (i *Icinga2APIResponse, err error) {
+	resp, err := a.queryObjects(objType, opts)
+	if err != nil {
+		return i, err
+	}
+	defer resp.Body.Close()
+	results, err := collectObjects(resp.Body)
 	if err != nil {
-		return m, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+		return i, err
 	}
-	return i.GetHosts(), nil
+	return &Icinga2APIResponse{Results: results}, nil
 }
 
-func (a *API) GetServices() (m []monitoring.Service, err error) {
-	return a.GetServicesByFilter("")
+// queryObjects sends query to /v1/objects/<objType> endpoint, caller has to close the body
+func (a *API) queryObjects(objType string, opts QueryOptions) (*http.Response, error) {
+	req, err := http.NewRequest("GET", a.URL.String()+"/v1/objects/"+objType, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.URL.RawQuery = opts.query().Encode()
+	if len(a.User) > 0 {
+		req.SetBasicAuth(a.User, a.Pass)
+	}
+	return a.client().Do(req)
 }
 
-func (a *API) GetServicesByFilter(filter string) (m []monitoring.Service, err error) {
-	client := httpClient()
-	req, err := http.NewRequest("GET", a.URL.String()+"/v1/objects/Services", nil)
+// request sends data (if not nil) encoded as JSON to the API path and returns response body regardless of HTTP status
+func (a *API) request(method string, path string, data interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding json: %s", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+	req, err := http.NewRequest(method, a.URL.String()+path, reqBody)
 	if err != nil {
-		return m, err
+		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
-	if filter != "" {
-		q := req.URL.Query()
-		q.Add("filter", filter)
-		req.URL.RawQuery = q.Encode()
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 	if len(a.User) > 0 {
 		req.SetBasicAuth(a.User, a.Pass)
 	}
-	resp, err := client.Do(req)
+	resp, err := a.client().Do(req)
 	if err != nil {
-		return m, err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	var i Icinga2APIResponse
-	body, err := ioutil.ReadAll(resp.Body)
+	return ioutil.ReadAll(resp.Body)
+}
+
+// statusError decodes status response and returns error if request failed or any of the results has error code
+func statusError(body []byte, what string) error {
+	var i Icinga2StatusResponseOk
+	err := json.Unmarshal(body, &i)
 	if err != nil {
-		return m, err
+		return fmt.Errorf("error decoding json: %s | %s", err, string(body))
 	}
-	err = json.Unmarshal(body, &i)
-	if err != nil {
-		return m, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+	if len(i.Results) == 0 {
+		var e Icinga2StatusPart
+		_ = json.Unmarshal(body, &e)
+		return fmt.Errorf("%s: [%d] %s", what, int(e.Error), e.Status)
+	}
+	for _, r := range i.Results {
+		if r.Code >= 300 {
+			if len(r.Errors) > 0 {
+				return fmt.Errorf("%s: [%d] %s: %s", what, int(r.Code), r.Status, strings.Join(r.Errors, "; "))
+			}
+			return fmt.Errorf("%s: [%d] %s", what, int(r.Code), r.Status)
+		}
 	}
-	return i.GetServices(), nil
+	return nil
 }
 
 type Downtime struct {
@@ -136,6 +202,7 @@ type downtimeRequest struct {
 	Filter      string `json:"filter"`
 	StartTime   int    `json:"start_time"`
 	EndTime     int    `json:"end_time"`
+	Fixed       bool   `json:"fixed"`
 	Duration    int    `json:"duration,omitempty"`
 	Author      string `json:"author"`
 	Comment     string `json:"comment"`
@@ -147,55 +214,62 @@ func (a *API) ScheduleHostDowntime(host string, downtime Downtime) (downtimedHos
 	return a.ScheduleHostDowntimeByFilter(`match("`+host+`", host.name)`, downtime)
 }
 
-// ScheduleHostDowntime schedules downtime on hostname by the icinga filters
+// ScheduleHostDowntime schedules downtime on hostname by the icinga filters.
+// Returns only hosts downtime was scheduled for, use ScheduleHostDowntimeByFilterWithOptions to get failures
 func (a *API) ScheduleHostDowntimeByFilter(filter string, downtime Downtime) (downtimedHosts []string, err error) {
+	results, err := a.ScheduleHostDowntimeByFilterWithOptions(filter, downtime, ActionOptions{})
+	return results.Succeeded().Objects(), err
+}
+
+// ScheduleHostDowntimeByFilterWithOptions schedules downtime on hosts matched by filter and returns result for every object.
+// In dry run it returns hosts that would be downtimed
+func (a *API) ScheduleHostDowntimeByFilterWithOptions(filter string, downtime Downtime, opts ActionOptions) (results ActionResults, err error) {
 	err = downtime.Validate()
 	if err != nil {
-		return downtimedHosts, err
+		return results, err
+	}
+	if filter == "" {
+		return results, fmt.Errorf("action schedule-downtime needs filter")
 	}
+	matched, run, err := a.guardAction("Host", filter, opts)
+	if !run {
+		return dryRunResults(matched), err
+	}
+	return a.scheduleDowntime("Host", filter, downtime)
+}
 
-	reqData := downtimeRequest{
-		Type: "Host",
-		// TODO wildcard protection
-		Filter:      filter,
-		StartTime:   int(downtime.Start.UTC().Unix()),
-		EndTime:     int(downtime.End.UTC().Unix()),
-		Author:      downtime.Author,
-		Comment:     downtime.Comment,
-		AllServices: !downtime.NoAllServices,
-	}
-
-	jsonData, err := json.Marshal(reqData)
-	req, err := http.NewRequest("POST", a.URL.String()+"/v1/actions/schedule-downtime", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return downtimedHosts, err
+// scheduleDowntime schedules downtime on objects of type (Host or Service) matched by filter
+func (a *API) scheduleDowntime(objType string, filter string, downtime Downtime) (ActionResults, error) {
+	// empty filter would select all objects
+	if filter == "" {
+		return nil, fmt.Errorf("action schedule-downtime needs filter")
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	if len(a.User) > 0 {
-		req.SetBasicAuth(a.User, a.Pass)
+	reqData := downtimeRequest{
+		Type:      objType,
+		Filter:    filter,
+		StartTime: int(downtime.Start.UTC().Unix()),
+		EndTime:   int(downtime.End.UTC().Unix()),
+		Fixed:     !downtime.Flexible,
+		Author:    downtime.Author,
+		Comment:   downtime.Comment,
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return downtimedHosts, err
+	if downtime.Flexible {
+		reqData.Duration = int(downtime.Duration.Seconds())
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return downtimedHosts, err
+	if objType == "Host" {
+		reqData.AllServices = !downtime.NoAllServices
 	}
-	var i Icinga2StatusResponseOk
-	err = json.Unmarshal(body, &i)
+	results, err := a.postAction("schedule-downtime", reqData)
 	if err != nil {
-		return downtimedHosts, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+		return results, fmt.Errorf("error while setting downtime for filter [%s]: %s", filter, err)
 	}
-	if len(i.Results) == 0 {
-		var e Icinga2StatusPart
-		err := json.Unmarshal(body, &e)
-		if err != nil {
-			return downtimedHosts, fmt.Errorf("error decoding json: %s | %s", err, string(body))
-		}
-		return downtimedHosts, fmt.Errorf("error while setting downtime for filter [%s]: zero hosts returned: [%s]", filter, string(body))
+	if len(results) == 0 {
+		return results, fmt.Errorf("error while setting downtime for filter [%s]: zero objects returned", filter)
 	}
-	return i.GetDowntimeList(), nil
+	return results, nil
+}
+
+// quoteString quotes string for use as literal in Icinga2 filter expressions
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + `"`
 }
@@ -2,11 +2,11 @@ package icinga2
 
 import (
 	"fmt"
+	"github.com/efigence/go-icinga2/internal/icingatest"
 	"github.com/efigence/go-monitoring"
 	"github.com/stretchr/testify/assert"
-	"io/ioutil"
-	"net/http"
-	"net/http/httptest"
+	"github.com/stretchr/testify/require"
+	"net/url"
 	"testing"
 	"time"
 )
@@ -21,27 +21,9 @@ func (t testLogger) Printf(format string, v ...interface{}) {
 
 }
 
-type testServ struct {
-	*httptest.Server
-	reqBody string
-}
-
-func testServer(t *testing.T, path string, filename string) *testServ {
-	var tts = &testServ{}
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		f, err := ioutil.ReadFile("testdata/" + filename)
-		assert.Equal(t, path, r.URL.Path)
-		assert.Nil(t, err)
-		assert.Equal(t,"application/json",r.Header.Get("accept"),"accept header")
-		if r.Method == http.MethodPost {
-			assert.Equal(t, "application/json", r.Header.Get("content-type"),"content-type header")
-		}
-		b, _ := ioutil.ReadAll(r.Body)
-		tts.reqBody = string(b)
-		w.Write(f)
-	}))
-	tts.Server = ts
-	return tts
+// testServer serves file on path
+func testServer(t *testing.T, path string, filename string) *icingatest.Server {
+	return icingatest.NewServer(t, "testdata", map[string]string{path: filename})
 }
 
 func TestAPI_GetHosts(t *testing.T) {
@@ -113,9 +95,9 @@ func TestAPI_ScheduleHostDowntime(t *testing.T) {
 		assert.Nil(t, err2)
 	})
 	t.Run("request json", func(tt *testing.T) {
-		assert.Contains(tt, ts.reqBody, `"filter":"match(\"t1-host1\", host.name)"`)
-		assert.Contains(tt, ts.reqBody, `"author":"`+t.Name()+`"`)
-		assert.Contains(tt, ts.reqBody, `"comment":"c:`+t.Name()+`"`)
+		assert.Contains(tt, ts.LastBody(), `"filter":"match(\"t1-host1\", host.name)"`)
+		assert.Contains(tt, ts.LastBody(), `"author":"`+t.Name()+`"`)
+		assert.Contains(tt, ts.LastBody(), `"comment":"c:`+t.Name()+`"`)
 	})
 	t.Run("host count", func(t *testing.T) {
 		assert.Len(t, hosts, 2)
@@ -139,3 +121,14 @@ func TestAPI_ScheduleHostDowntime_NoHost(t *testing.T) {
 		assert.Error(t, err2)
 	})
 }
+
+func TestAPI_ZeroClient(t *testing.T) {
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.Nil(t, err)
+	api := &API{URL: u, User: TestUser, Pass: TestPass}
+	hosts, err := api.GetHosts()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, hosts)
+}
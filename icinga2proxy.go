@@ -7,67 +7,232 @@ import (
 )
 
 type Icinga2ServerConfig struct {
-	ServerURL string `yaml:"server_url"`
-	User string `yaml:"user"`
-	Pass string `yaml:"pass"`
+	ServerURL string `yaml:"server_url" json:"server_url"`
+	User      string `yaml:"user" json:"user"`
+	Pass      string `yaml:"pass" json:"pass"`
+	// PEM file with CA used to verify server certificate, system pool is used if empty
+	CACert string `yaml:"ca_cert" json:"ca_cert"`
+	// client certificate and key for certificate auth
+	ClientCert         string   `yaml:"client_cert" json:"client_cert"`
+	ClientKey          string   `yaml:"client_key" json:"client_key"`
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+	Timeout            Duration `yaml:"timeout" json:"timeout"`
+	// arbitrary labels (site, env, team...) attached to every object returned from this server
+	Labels map[string]string `yaml:"labels" json:"labels"`
+}
+
+// AnnotatedHost is a host with info about Icinga2 server it came from
+type AnnotatedHost struct {
+	monitoring.Host
+	Server string            `json:"server"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AnnotatedService is a service with info about Icinga2 server it came from
+type AnnotatedService struct {
+	monitoring.Service
+	Server string            `json:"server"`
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type Proxy struct {
-	servers map[string]*API
+	lock             sync.RWMutex
+	servers          map[string]*API
+	labels           map[string]map[string]string
 	conflictResolver func(icinga2ServerName string, hostName string) (newName string)
+
+	hostStateMapper    HostStateMapper
+	serviceStateMapper ServiceStateMapper
 }
 
 // NewProxy creates Icinga2 proxy that will merge data from all servers
-func NewProxy(servers map[string]Icinga2ServerConfig,) (*Proxy, error) {
-	p := &Proxy{
-		servers: make(map[string]*API,0),
-		conflictResolver: func(icinga2ServerName string, hostName string) (newName string) {
-			return fmt.Sprintf("%s_%s",hostName,icinga2ServerName)
-		},
+func NewProxy(servers map[string]Icinga2ServerConfig) (*Proxy, error) {
+	return NewProxyFromConfig(&ProxyConfig{Servers: servers})
+}
+
+// NewProxyFromConfig creates Icinga2 proxy from loaded config
+func NewProxyFromConfig(cfg *ProxyConfig) (*Proxy, error) {
+	p := &Proxy{}
+	err := p.Reload(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload replaces server set and conflict strategy. Requests already in progress finish on old set of servers.
+// Config without conflict strategy keeps the current resolver, e.g. one set by SetConflictResolver
+func (a *Proxy) Reload(cfg *ProxyConfig) error {
+	servers := make(map[string]*API, 0)
+	labels := make(map[string]map[string]string, 0)
+	for k, s := range cfg.Servers {
+		server, err := NewFromConfig(s)
+		if err != nil {
+			return fmt.Errorf("error configuring icinga2 instance at %s:%s", s.ServerURL, err)
+		}
+		servers[k] = server
+		labels[k] = s.Labels
 	}
-	for k, s := range servers {
-		server, err := New(s.ServerURL,s.User,s.Pass)
+	if cfg.RejectDuplicateNodes {
+		err := checkDuplicateNodes(servers)
 		if err != nil {
-			return nil, fmt.Errorf("error configuring icinga2 instance at %s:%s", s.ServerURL, err)
+			return err
 		}
-		p.servers[k] = server
 	}
-	return p,nil
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, server := range servers {
+		server.SetHostStateMapper(a.hostStateMapper)
+		server.SetServiceStateMapper(a.serviceStateMapper)
+	}
+	a.servers = servers
+	a.labels = labels
+	if cfg.ConflictStrategy != "" || a.conflictResolver == nil {
+		a.conflictResolver = conflictResolverFor(cfg.ConflictStrategy)
+	}
+	return nil
 }
 
-func sliceToMapHost(s []monitoring.Host) (map[string]monitoring.Host) {
-	m := make(map[string]monitoring.Host,len(s))
+// SetStateMappers sets state mapping functions used for all servers, nil restores the default
+func (a *Proxy) SetStateMappers(host HostStateMapper, service ServiceStateMapper) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.hostStateMapper = host
+	a.serviceStateMapper = service
+	// server set is replaced on reload so it is safe to just create a new one
+	servers := make(map[string]*API, len(a.servers))
+	for k, v := range a.servers {
+		server := *v
+		server.SetHostStateMapper(host)
+		server.SetServiceStateMapper(service)
+		servers[k] = &server
+	}
+	a.servers = servers
+}
+
+// getServers returns current server set. It is never modified in place so it is safe to use without lock
+func (a *Proxy) getServers() map[string]*API {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.servers
+}
+
+// Servers returns client of each configured server, for queries Proxy doesn't merge itself
+func (a *Proxy) Servers() map[string]*API {
+	servers := a.getServers()
+	out := make(map[string]*API, len(servers))
+	for k, v := range servers {
+		out[k] = v
+	}
+	return out
+}
+
+// WithLabels returns Proxy limited to servers that have all of the given labels, so queries are only sent to them.
+// It uses server set current at the time of the call and is not affected by later reloads
+func (a *Proxy) WithLabels(selector map[string]string) *Proxy {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	p := &Proxy{
+		servers:            make(map[string]*API, 0),
+		labels:             make(map[string]map[string]string, 0),
+		conflictResolver:   a.conflictResolver,
+		hostStateMapper:    a.hostStateMapper,
+		serviceStateMapper: a.serviceStateMapper,
+	}
+	for k, v := range a.servers {
+		if labelsMatch(a.labels[k], selector) {
+			p.servers[k] = v
+			p.labels[k] = a.labels[k]
+		}
+	}
+	return p
+}
+
+func labelsMatch(labels map[string]string, selector map[string]string) bool {
+	for k, v := range selector {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ServerLabels returns labels of each configured server
+func (a *Proxy) ServerLabels() map[string]map[string]string {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.labels
+}
+
+func (a *Proxy) resolveConflict(icinga2ServerName string, hostName string) string {
+	a.lock.RLock()
+	f := a.conflictResolver
+	a.lock.RUnlock()
+	return f(icinga2ServerName, hostName)
+}
+
+func sliceToMapHost(s []monitoring.Host) map[string]monitoring.Host {
+	m := make(map[string]monitoring.Host, len(s))
 	for _, a := range s {
 		m[a.Host] = a
 	}
 	return m
 }
 
-
-func (a *Proxy)SetConflictResolver(f func(icinga2ServerName string, hostName string) (newName string)) {
-
+// SetConflictResolver sets function used to rename hosts existing on more than one server
+func (a *Proxy) SetConflictResolver(f func(icinga2ServerName string, hostName string) (newName string)) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.conflictResolver = f
 }
 
 func (a *Proxy) GetHosts() (m []monitoring.Host, err error) {
-return a.GetHostsByFilter("")
+	return a.GetHostsByFilter("")
 
 }
 func (a *Proxy) GetHostsByFilter(filter string) (m []monitoring.Host, err error) {
+	res, errs := a.fetchHosts(filter)
+	return a.mergeHosts(res), proxyError(errs)
+}
+
+// fetchHosts queries all servers in parallel and returns per-server results
+func (a *Proxy) fetchHosts(filter string) (map[string][]monitoring.Host, map[string]error) {
 	var wg sync.WaitGroup
-	res := make( map[string]map[string]monitoring.Host)
-	errs := make( map[string]error)
-	for k, v := range a.servers {
+	var lock sync.Mutex
+	res := make(map[string][]monitoring.Host)
+	errs := make(map[string]error)
+	for k, v := range a.getServers() {
 		wg.Add(1)
-		func(k string) {
-			var z []monitoring.Host
-			z, errs[k]  = v.GetHostsByFilter(filter)
-			res[k]= sliceToMapHost(z)
+		go func(k string, s *API) {
+			z, err := s.GetHostsByFilter(filter)
+			lock.Lock()
+			res[k], errs[k] = z, err
+			lock.Unlock()
 			wg.Done()
-		}(k)
+		}(k, v)
 	}
-	collisionMap := make(map[string]int8,0)
-	out := make([]monitoring.Host,0)
 	wg.Wait()
+	return res, errs
+}
+
+// mergeHosts merges per-server host lists, renaming hosts that exist on more than one server
+func (a *Proxy) mergeHosts(perServer map[string][]monitoring.Host) []monitoring.Host {
+	annotated := a.mergeHostsAnnotated(perServer)
+	out := make([]monitoring.Host, len(annotated))
+	for i, h := range annotated {
+		out[i] = h.Host
+	}
+	return out
+}
+
+func (a *Proxy) mergeHostsAnnotated(perServer map[string][]monitoring.Host) []AnnotatedHost {
+	res := make(map[string]map[string]monitoring.Host, len(perServer))
+	for k, v := range perServer {
+		res[k] = sliceToMapHost(v)
+	}
+	labels := a.ServerLabels()
+	collisionMap := make(map[string]int8, 0)
+	out := make([]AnnotatedHost, 0)
 	for _, hosts := range res {
 		for host, _ := range hosts {
 			collisionMap[host]++
@@ -76,106 +241,126 @@ func (a *Proxy) GetHostsByFilter(filter string) (m []monitoring.Host, err error)
 	for icingaServer, hosts := range res {
 		for host, check := range hosts {
 			if collisionMap[host] > 1 {
-				check.Host = a.conflictResolver(icingaServer,host)
+				check.Host = a.resolveConflict(icingaServer, host)
 			}
-			out = append(out,check)
+			out = append(out, AnnotatedHost{
+				Host:   check,
+				Server: icingaServer,
+				Labels: labels[icingaServer],
+			})
 		}
 	}
-	errOut := true
-	for _, err := range errs {
-		if err == nil {
-			errOut = false
-		}
-	}
-	// TODO figure out how to signal that. Err handler for logging ?
-	if errOut {
-		return out, fmt.Errorf("error: [%+v]", errs)
-	} else {
-		return out, nil
-	}
-
+	return out
+}
 
+// GetHostsAnnotated returns hosts matching filter, each with name and labels of the server it came from
+func (a *Proxy) GetHostsAnnotated(filter string) (m []AnnotatedHost, err error) {
+	res, errs := a.fetchHosts(filter)
+	return a.mergeHostsAnnotated(res), proxyError(errs)
 }
+
 func (a *Proxy) GetServices() (m []monitoring.Service, err error) {
 	return a.GetServicesByFilter("")
 }
 func (a *Proxy) GetServicesByFilter(filter string) (m []monitoring.Service, err error) {
+	res, errs := a.fetchServices(filter)
+	return a.mergeServices(res), proxyError(errs)
+}
+
+// fetchServices queries all servers in parallel and returns per-server results
+func (a *Proxy) fetchServices(filter string) (map[string][]monitoring.Service, map[string]error) {
 	var wg sync.WaitGroup
-	res := make( map[string][]monitoring.Service)
-	errs := make( map[string]error)
-	for k, v := range a.servers {
+	var lock sync.Mutex
+	res := make(map[string][]monitoring.Service)
+	errs := make(map[string]error)
+	for k, v := range a.getServers() {
 		wg.Add(1)
-		func(k string) {
-			res[k], errs[k]  = v.GetServicesByFilter(filter)
+		go func(k string, s *API) {
+			z, err := s.GetServicesByFilter(filter)
+			lock.Lock()
+			res[k], errs[k] = z, err
+			lock.Unlock()
 			wg.Done()
-		}(k)
+		}(k, v)
 	}
-	partialCollisionMap := make(map[string]map[string]bool)
-	collisionMap := make(map[string]int8,0)
-	out := make([]monitoring.Service,0)
 	wg.Wait()
+	return res, errs
+}
+
+// mergeServices merges per-server service lists, renaming hosts that exist on more than one server
+func (a *Proxy) mergeServices(res map[string][]monitoring.Service) []monitoring.Service {
+	annotated := a.mergeServicesAnnotated(res)
+	out := make([]monitoring.Service, len(annotated))
+	for i, s := range annotated {
+		out[i] = s.Service
+	}
+	return out
+}
+
+func (a *Proxy) mergeServicesAnnotated(res map[string][]monitoring.Service) []AnnotatedService {
+	labels := a.ServerLabels()
+	partialCollisionMap := make(map[string]map[string]bool)
+	collisionMap := make(map[string]int8, 0)
+	out := make([]AnnotatedService, 0)
 	for h, services := range res {
-		partialCollisionMap[h] = make(map[string]bool,0)
+		partialCollisionMap[h] = make(map[string]bool, 0)
 		for _, serviceData := range services {
-			partialCollisionMap[h][serviceData.Host]=true
+			partialCollisionMap[h][serviceData.Host] = true
 		}
 	}
 	for _, services := range partialCollisionMap {
-		for host, _ :=  range services {
+		for host, _ := range services {
 			collisionMap[host]++
 		}
 	}
 	for icingaServer, services := range res {
 		for _, check := range services {
 			if collisionMap[check.Host] > 1 {
-				check.Host=a.conflictResolver(icingaServer,check.Host)
+				check.Host = a.resolveConflict(icingaServer, check.Host)
 			}
-			out = append(out,check)
+			out = append(out, AnnotatedService{
+				Service: check,
+				Server:  icingaServer,
+				Labels:  labels[icingaServer],
+			})
 
 		}
 	}
-	errOut := true
-	for _, err := range errs {
-		if err == nil {
-			errOut = false
-		}
-	}
-	// TODO figure out how to signal that. Err handler for logging ?
-	if errOut {
-		return out, fmt.Errorf("error: [%+v]", errs)
-	} else {
-		return out, nil
-	}
-
-
+	return out
+}
 
+// GetServicesAnnotated returns services matching filter, each with name and labels of the server it came from
+func (a *Proxy) GetServicesAnnotated(filter string) (m []AnnotatedService, err error) {
+	res, errs := a.fetchServices(filter)
+	return a.mergeServicesAnnotated(res), proxyError(errs)
 }
-func (a *Proxy) ScheduleHostDowntime(host string,downtime Downtime) (downtimedHosts []string, err error) {
-	return a.ScheduleHostDowntimeByFilter(`match("` + host + `", host.name)`,downtime)
+
+// each calls f for every server in parallel and returns per-server errors. f has to do its own locking
+// Each calls f for every server in parallel and returns error of each of them, for operations Proxy doesn't provide itself
+func (a *Proxy) Each(f func(name string, s *API) error) map[string]error {
+	return a.each(f)
 }
-func (a *Proxy) ScheduleHostDowntimeByFilter(filter string, downtime Downtime) (downtimedHosts []string, err error) {
-	res := make( map[string][]string)
-	errs := make( map[string]error)
+
+func (a *Proxy) each(f func(name string, s *API) error) map[string]error {
 	var wg sync.WaitGroup
-	for k, v := range a.servers {
+	var lock sync.Mutex
+	errs := make(map[string]error)
+	for k, v := range a.getServers() {
 		wg.Add(1)
-		func(k string, s *API) {
-			res[k], errs[k]  = s.ScheduleHostDowntimeByFilter(filter,downtime)
+		go func(k string, s *API) {
+			err := f(k, s)
+			lock.Lock()
+			errs[k] = err
+			lock.Unlock()
 			wg.Done()
-		}(k,v)
+		}(k, v)
 	}
 	wg.Wait()
-	downtimeMap := make(map[string]bool)
-	for _, hosts := range res {
-		for _, host := range hosts{
-			downtimeMap[host]=true
-		}
-	}
-	out := make([]string,0)
-	for h, _ := range downtimeMap {
-		out = append(out, h)
-	}
+	return errs
+}
 
+// proxyError returns error only if every server failed
+func proxyError(errs map[string]error) error {
 	errOut := true
 	for _, err := range errs {
 		if err == nil {
@@ -184,10 +369,31 @@ func (a *Proxy) ScheduleHostDowntimeByFilter(filter string, downtime Downtime) (
 	}
 	// TODO figure out how to signal that. Err handler for logging ?
 	if errOut {
-		return out, fmt.Errorf("[%+v]",errs)
+		return fmt.Errorf("error: [%+v]", errs)
 	} else {
-		return out, nil
+		return nil
 	}
+}
 
+func (a *Proxy) ScheduleHostDowntime(host string, downtime Downtime) (downtimedHosts []string, err error) {
+	return a.ScheduleHostDowntimeByFilter(`match("`+host+`", host.name)`, downtime)
+}
+func (a *Proxy) ScheduleHostDowntimeByFilter(filter string, downtime Downtime) (downtimedHosts []string, err error) {
+	results, err := a.ScheduleHostDowntimeByFilterWithOptions(filter, downtime, ActionOptions{})
+	return results.Succeeded().Objects(), err
+}
 
+// ScheduleHostDowntimeByFilterWithOptions schedules downtime on all servers and returns result for every object with Server set.
+// Object limit applies to the total number of matched hosts. In dry run it returns hosts that would be downtimed
+func (a *Proxy) ScheduleHostDowntimeByFilterWithOptions(filter string, downtime Downtime, opts ActionOptions) (results ActionResults, err error) {
+	err = downtime.Validate()
+	if err != nil {
+		return results, err
+	}
+	if filter == "" {
+		return results, fmt.Errorf("action schedule-downtime needs filter")
+	}
+	return a.proxyAction("Host", filter, opts, func(s *API) (ActionResults, error) {
+		return s.scheduleDowntime("Host", filter, downtime)
+	})
 }
@@ -13,7 +13,7 @@ func TestProxy_GetHosts_Single(t *testing.T) {
 	log = testLogger{}
 	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
 	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
-		"s1" : {ts.URL, TestUser, TestPass},
+		"s1" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
 	})
 	hosts, err2 := Api.GetHosts()
 	t.Run("parse input", func(t *testing.T) {
@@ -39,8 +39,8 @@ func TestProxy_GetHosts_Dedup(t *testing.T) {
 	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
 	ts2 := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts_dedup.json")
 	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
-		"s1" : {ts.URL, TestUser, TestPass},
-		"s2" : {ts2.URL, TestUser, TestPass},
+		"s1" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2" : {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
 	})
 	hosts, err2 := Api.GetHosts()
 	t.Run("parse input", func(t *testing.T) {
@@ -70,7 +70,7 @@ func TestProxy_GetServices_Single(t *testing.T) {
 	log = testLogger{}
 	ts := testServer(t, "/v1/objects/Services", "v1.objects.services.json")
 	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
-		"s1" : {ts.URL, TestUser, TestPass},
+		"s1" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
 	})
 	services, err2 := Api.GetServices()
 	t.Run("parse input", func(t *testing.T) {
@@ -102,8 +102,8 @@ func TestProxy_GetServices_Dedup(t *testing.T) {
 	ts := testServer(t, "/v1/objects/Services", "v1.objects.services.json")
 	ts2 := testServer(t, "/v1/objects/Services", "v1.objects.services_dedup.json")
 	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
-		"s1" : {ts.URL, TestUser, TestPass},
-		"s2" : {ts2.URL, TestUser, TestPass},
+		"s1" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2" : {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
 	})
 	services, err2 := Api.GetServices()
 	t.Run("parse input", func(t *testing.T) {
@@ -138,8 +138,8 @@ func TestProxy_ScheduleHostDowntime(t *testing.T) {
 	log = testLogger{}
 	ts := testServer(t, "/v1/actions/schedule-downtime", "v1.actions.schedule-downtime.json")
 	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
-		"s1" : {ts.URL, TestUser, TestPass},
-		"s2" : {ts.URL, TestUser, TestPass},
+		"s1" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
 	})
 	hosts, err2 := Api.ScheduleHostDowntime("t1-host1", Downtime{
 		Flexible:      false,
@@ -155,9 +155,9 @@ func TestProxy_ScheduleHostDowntime(t *testing.T) {
 		assert.Nil(t, err2)
 	})
 	t.Run("request json", func(tt *testing.T) {
-		assert.Contains(tt, ts.reqBody, `"filter":"match(\"t1-host1\", host.name)"`)
-		assert.Contains(tt, ts.reqBody, `"author":"`+t.Name()+`"`)
-		assert.Contains(tt, ts.reqBody, `"comment":"c:`+t.Name()+`"`)
+		assert.Contains(tt, ts.LastBody(), `"filter":"match(\"t1-host1\", host.name)"`)
+		assert.Contains(tt, ts.LastBody(), `"author":"`+t.Name()+`"`)
+		assert.Contains(tt, ts.LastBody(), `"comment":"c:`+t.Name()+`"`)
 	})
 	t.Run("host count", func(t *testing.T) {
 		assert.Len(t, hosts, 2)
@@ -169,7 +169,7 @@ func TestProxy_ScheduleHostDowntime_NoHost(t *testing.T) {
 	log = testLogger{}
 	ts := testServer(t, "/v1/actions/schedule-downtime", "error.no-objects-found.json")
 	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
-		"s1" : {ts.URL, TestUser, TestPass},
+		"s1" : {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
 	})
 	_, err2 := Api.ScheduleHostDowntime("t1-host1", Downtime{
 		Flexible:      false,
@@ -183,8 +183,61 @@ func TestProxy_ScheduleHostDowntime_NoHost(t *testing.T) {
 	t.Run("parse input", func(t *testing.T) {
 		assert.Nil(t, err1)
 		assert.Error(t, err2)
-		assert.Contains(t,ts.reqBody,`"author":"testAuthor"`)
-		assert.Contains(t,ts.reqBody,`"comment":"testComment"`)
-		assert.Contains(t,ts.reqBody,`"filter":"match(\"t1-host1\", host.name)"`)
+		assert.Contains(t,ts.LastBody(),`"author":"testAuthor"`)
+		assert.Contains(t,ts.LastBody(),`"comment":"testComment"`)
+		assert.Contains(t,ts.LastBody(),`"filter":"match(\"t1-host1\", host.name)"`)
 	})
 }
+
+func TestProxy_Labels(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
+	ts2 := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts_dedup.json")
+	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass, Labels: map[string]string{"site": "dc1", "env": "prod"}},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass, Labels: map[string]string{"site": "dc2", "env": "prod"}},
+	})
+	assert.Nil(t, err1)
+	t.Run("annotated", func(t *testing.T) {
+		hosts, err := Api.GetHostsAnnotated("")
+		assert.Nil(t, err)
+		assert.Len(t, hosts, 14)
+		v := make(map[string]AnnotatedHost, 0)
+		for _, h := range hosts {
+			v[h.Host.Host] = h
+		}
+		assert.Equal(t, "s1", v["t1-host1_s1"].Server)
+		assert.Equal(t, "dc1", v["t1-host1_s1"].Labels["site"])
+		assert.Equal(t, "s2", v["t2-lb1"].Server)
+		assert.Equal(t, "dc2", v["t2-lb1"].Labels["site"])
+	})
+	t.Run("label filter", func(t *testing.T) {
+		hosts, err := Api.WithLabels(map[string]string{"site": "dc2"}).GetHostsAnnotated("")
+		assert.Nil(t, err)
+		assert.Len(t, hosts, 7)
+		for _, h := range hosts {
+			assert.Equal(t, "s2", h.Server)
+		}
+		assert.Len(t, Api.WithLabels(map[string]string{"env": "prod"}).getServers(), 2)
+		assert.Len(t, Api.WithLabels(map[string]string{"env": "dev"}).getServers(), 0)
+		servers := Api.WithLabels(map[string]string{"site": "dc1"}).Servers()
+		assert.Len(t, servers, 1)
+		assert.NotNil(t, servers["s1"])
+	})
+}
+
+func TestProxy_GetServicesAnnotated(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Services", "v1.objects.services.json")
+	Api, err1 := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass, Labels: map[string]string{"team": "ops"}},
+	})
+	assert.Nil(t, err1)
+	services, err := Api.GetServicesAnnotated("")
+	assert.Nil(t, err)
+	assert.Len(t, services, 7)
+	for _, s := range services {
+		assert.Equal(t, "s1", s.Server)
+		assert.Equal(t, "ops", s.Labels["team"])
+	}
+}
@@ -0,0 +1,101 @@
+// Package icingatest provides fake Icinga2 API server serving recorded responses, for tests of the library and its commands
+package icingatest
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Request is request received by Server
+type Request struct {
+	Method string
+	URI    string
+	Body   string
+}
+
+// Query returns URL query of the request
+func (r Request) Query() url.Values {
+	u, _ := url.Parse(r.URI)
+	return u.Query()
+}
+
+// Server serves a file per URL path and records requests
+type Server struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests []Request
+}
+
+// NewServer serves files from dir by URL path. File name can be prefixed by HTTP status to respond with, like "500 error.json".
+// Path "*" sets response for paths without their own file, otherwise request to unknown path fails the test
+func NewServer(t testing.TB, dir string, files map[string]string) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		s.lock.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, URI: r.URL.RequestURI(), Body: string(b)})
+		s.lock.Unlock()
+		assert.Equal(t, "application/json", r.Header.Get("accept"), "accept header")
+		if r.Method == http.MethodPost {
+			assert.Equal(t, "application/json", r.Header.Get("content-type"), "content-type header")
+		}
+		filename, ok := files[r.URL.Path]
+		if !ok {
+			filename, ok = files["*"]
+		}
+		if !assert.True(t, ok, "unexpected path %s", r.URL.Path) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		status := http.StatusOK
+		if code, name, found := strings.Cut(filename, " "); found {
+			status, _ = strconv.Atoi(code)
+			filename = name
+		}
+		f, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		assert.Nil(t, err)
+		w.WriteHeader(status)
+		w.Write(f)
+	}))
+	return s
+}
+
+// Requests returns requests received so far
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// LastQuery returns URL query of the last request
+func (s *Server) LastQuery() url.Values {
+	r := s.Requests()
+	if len(r) == 0 {
+		return nil
+	}
+	return r[len(r)-1].Query()
+}
+
+// LastBody returns body of the last request, empty if there was none
+func (s *Server) LastBody() string {
+	r := s.Requests()
+	if len(r) == 0 {
+		return ""
+	}
+	return r[len(r)-1].Body
+}
+
+// Reset forgets received requests
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = nil
+}
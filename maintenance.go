@@ -0,0 +1,109 @@
+package icinga2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DowntimeTarget selects objects to be downtimed
+type DowntimeTarget struct {
+	// Host or Service
+	Type   string
+	Filter string
+}
+
+// HostsTarget selects hosts by filter, their services are downtimed too unless Downtime.NoAllServices is set
+func HostsTarget(filter string) DowntimeTarget {
+	return DowntimeTarget{Type: "Host", Filter: filter}
+}
+
+// HostNamesTarget selects hosts by exact names
+func HostNamesTarget(names ...string) DowntimeTarget {
+	filters := make([]Filter, 0, len(names))
+	for _, name := range names {
+		filters = append(filters, Eq("host.name", name))
+	}
+	return HostsTarget(Or(filters...).String())
+}
+
+// ServicesTarget selects services by filter
+func ServicesTarget(filter string) DowntimeTarget {
+	return DowntimeTarget{Type: "Service", Filter: filter}
+}
+
+// ScheduleDowntime schedules downtime on target and returns result for every object, including failed ones.
+// Names of created downtimes are in ActionResult.Name. In dry run it returns objects that would be downtimed
+func (a *API) ScheduleDowntime(target DowntimeTarget, downtime Downtime, opts ActionOptions) (results ActionResults, err error) {
+	if target.Type != "Host" && target.Type != "Service" {
+		return results, fmt.Errorf("downtime target has to be Host or Service, not [%s]", target.Type)
+	}
+	if target.Filter == "" {
+		return results, fmt.Errorf("downtime target needs filter")
+	}
+	err = downtime.Validate()
+	if err != nil {
+		return results, err
+	}
+	matched, run, err := a.guardAction(target.Type, target.Filter, opts)
+	if !run {
+		return dryRunResults(matched), err
+	}
+	return a.scheduleDowntime(target.Type, target.Filter, downtime)
+}
+
+// RemoveDowntime removes downtime by name. Downtime that no longer exists (e.g. expired) is not an error.
+// Icinga2 removes child downtimes (like ones created for host services) together with their parent
+func (a *API) RemoveDowntime(name string) (results ActionResults, err error) {
+	results, err = a.postAction("remove-downtime", map[string]string{"type": "Downtime", "downtime": name})
+	if apiErr, ok := err.(*APIError); ok && apiErr.Code == 404 {
+		return ActionResults{}, nil
+	}
+	if err != nil {
+		return results, fmt.Errorf("error removing downtime %s: %s", name, err)
+	}
+	return results, nil
+}
+
+// WithDowntime schedules downtime on target, runs fn and then removes downtimes it created,
+// even if fn fails, panics or ctx is cancelled. Cleanup errors are returned together with error of fn
+func (a *API) WithDowntime(ctx context.Context, target DowntimeTarget, downtime Downtime, fn func(ctx context.Context) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	results, err := a.ScheduleDowntime(target, downtime, ActionOptions{})
+	if err != nil {
+		return fmt.Errorf("error scheduling downtime: %s", err)
+	}
+	names := results.Succeeded().Names()
+	defer func() {
+		cleanupErrs := make([]string, 0)
+		for _, name := range names {
+			removed, removeErr := a.RemoveDowntime(name)
+			if removeErr == nil {
+				removeErr = removed.Err()
+			}
+			if removeErr != nil {
+				cleanupErrs = append(cleanupErrs, fmt.Sprintf("%s: %s", name, removeErr))
+			}
+		}
+		if len(cleanupErrs) == 0 {
+			return
+		}
+		cleanupErr := fmt.Errorf("error removing downtimes: %s", strings.Join(cleanupErrs, "; "))
+		if err == nil {
+			err = cleanupErr
+		} else {
+			err = fmt.Errorf("%s; %s", err, cleanupErr)
+		}
+	}()
+	// don't run fn if some of the objects are not in downtime
+	if err = results.Err(); err != nil {
+		return fmt.Errorf("error scheduling downtime: %s", err)
+	}
+	err = fn(ctx)
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package icinga2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var maintenanceFiles = map[string]string{
+	"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.json",
+	"/v1/actions/remove-downtime":   "v1.actions.remove-downtime.json",
+}
+
+func removedDowntimes(t *testing.T, requests []icingatest.Request) []string {
+	names := make([]string, 0)
+	for _, r := range requests {
+		if r.URI != "/v1/actions/remove-downtime" {
+			continue
+		}
+		var req map[string]string
+		require.Nil(t, json.Unmarshal([]byte(r.Body), &req))
+		assert.Equal(t, "Downtime", req["type"])
+		names = append(names, req["downtime"])
+	}
+	return names
+}
+
+var createdDowntimes = []string{"t1-mon1!2316b99e-c70c-41ab-aaca-684fab53fa24", "t1-mon2!0117c82e-6b80-4997-8439-bd11bc5d7ee2"}
+
+func TestAPI_ScheduleDowntime(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", maintenanceFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	results, err := Api.ScheduleDowntime(ServicesTarget(`service.name=="HTTP"`), testDowntime(), ActionOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, createdDowntimes, results.Names())
+	assert.Equal(t, []string{"t1-mon1", "t1-mon2"}, results.Objects())
+	assert.Equal(t, 26, results[0].LegacyID)
+	var req map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(ts.Requests()[0].Body), &req))
+	assert.Equal(t, "Service", req["type"])
+	assert.Nil(t, req["all_services"], "only valid for hosts")
+	assert.Equal(t, true, req["fixed"])
+	assert.Nil(t, req["duration"], "only for flexible downtime")
+
+	flexible := testDowntime()
+	flexible.Flexible = true
+	flexible.Duration = 30 * time.Minute
+	_, err = Api.ScheduleDowntime(HostsTarget(`host.name=="t1-mon1"`), flexible, ActionOptions{})
+	require.Nil(t, err)
+	req = nil
+	require.Nil(t, json.Unmarshal([]byte(ts.Requests()[1].Body), &req))
+	assert.Equal(t, false, req["fixed"])
+	assert.Equal(t, float64(1800), req["duration"])
+
+	_, err = Api.ScheduleDowntime(DowntimeTarget{Type: "Host"}, testDowntime(), ActionOptions{})
+	assert.NotNil(t, err)
+	_, err = Api.ScheduleDowntime(DowntimeTarget{Type: "Zone", Filter: "true"}, testDowntime(), ActionOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestAPI_ScheduleDowntime_Options(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	results, err := Api.ScheduleDowntime(HostsTarget(`match("t1-*", host.name)`), testDowntime(), ActionOptions{DryRun: true})
+	require.Nil(t, err)
+	assert.Len(t, results, 7)
+	require.Len(t, ts.Requests(), 1, "only preview query should be sent")
+	assert.Equal(t, "GET", ts.Requests()[0].Method)
+
+	ts.Reset()
+	_, err = Api.ScheduleDowntime(HostsTarget(`match("t1-*", host.name)`), testDowntime(), ActionOptions{MaxObjects: 5})
+	_, ok := err.(*TooManyObjectsError)
+	assert.True(t, ok, "should be *TooManyObjectsError")
+	assert.Len(t, ts.Requests(), 1, "action should not be sent")
+}
+
+func TestHostNamesTarget(t *testing.T) {
+	assert.Equal(t, DowntimeTarget{Type: "Host", Filter: `(host.name=="a") || (host.name=="b")`}, HostNamesTarget("a", "b"))
+}
+
+func TestAPI_WithDowntime(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", maintenanceFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	called := false
+	err = Api.WithDowntime(context.Background(), HostNamesTarget("t1-mon1", "t1-mon2"), testDowntime(), func(ctx context.Context) error {
+		called = true
+		assert.Len(t, ts.Requests(), 1, "downtime should be scheduled before callback")
+		return nil
+	})
+	require.Nil(t, err)
+	assert.True(t, called)
+	assert.Equal(t, createdDowntimes, removedDowntimes(t, ts.Requests()))
+
+	t.Run("callback error", func(t *testing.T) {
+		ts.Reset()
+		err := Api.WithDowntime(context.Background(), HostsTarget("true"), testDowntime(), func(ctx context.Context) error {
+			return fmt.Errorf("deploy failed")
+		})
+		assert.EqualError(t, err, "deploy failed")
+		assert.Equal(t, createdDowntimes, removedDowntimes(t, ts.Requests()))
+	})
+	t.Run("panic", func(t *testing.T) {
+		ts.Reset()
+		assert.Panics(t, func() {
+			Api.WithDowntime(context.Background(), HostsTarget("true"), testDowntime(), func(ctx context.Context) error {
+				panic("boom")
+			})
+		})
+		assert.Equal(t, createdDowntimes, removedDowntimes(t, ts.Requests()))
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		ts.Reset()
+		ctx, cancel := context.WithCancel(context.Background())
+		err := Api.WithDowntime(ctx, HostsTarget("true"), testDowntime(), func(ctx context.Context) error {
+			cancel()
+			return nil
+		})
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, createdDowntimes, removedDowntimes(t, ts.Requests()))
+
+		ts.Reset()
+		err = Api.WithDowntime(ctx, HostsTarget("true"), testDowntime(), func(ctx context.Context) error {
+			t.Error("should not be called")
+			return nil
+		})
+		assert.Equal(t, context.Canceled, err)
+		assert.Empty(t, ts.Requests(), "nothing scheduled for cancelled context")
+	})
+}
+
+func TestAPI_WithDowntime_CleanupError(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime._1host.json",
+		"/v1/actions/remove-downtime":   "v1.actions.remove-downtime.error.json",
+	})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	err = Api.WithDowntime(context.Background(), HostsTarget("true"), testDowntime(), func(ctx context.Context) error {
+		return fmt.Errorf("deploy failed")
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "deploy failed; error removing downtimes: t1-mon1!478e8772-8b85-4c78-aef7-7c2e16f1a119: action failed for 1 of 1 objects: [500] Cannot remove downtime")
+}
+
+func TestAPI_RemoveDowntime_NotFound(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/actions/remove-downtime", "error.no-objects-found.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	results, err := Api.RemoveDowntime("t1-mon1!expired")
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
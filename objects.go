@@ -0,0 +1,240 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-monitoring"
+	"time"
+)
+
+// Timestamp is time decoded from Icinga2 float unix timestamp. 0 is decoded as zero time
+type Timestamp struct {
+	time.Time
+}
+
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	if f == 0 {
+		t.Time = time.Time{}
+	} else {
+		t.Time = unixTsToTs(f)
+	}
+	return nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
+	}
+	return json.Marshal(float64(t.UnixNano()) / float64(time.Second))
+}
+
+// SourceLocation is the place in config where object was defined
+type SourceLocation struct {
+	Path        string  `json:"path"`
+	FirstLine   float64 `json:"first_line"`
+	FirstColumn float64 `json:"first_column"`
+	LastLine    float64 `json:"last_line"`
+	LastColumn  float64 `json:"last_column"`
+}
+
+// Checkable contains attributes common to hosts and services. Numeric fields are floats as that's what API returns
+type Checkable struct {
+	Name           string         `json:"name"`
+	DisplayName    string         `json:"display_name"`
+	Active         bool           `json:"active"`
+	Paused         bool           `json:"paused"`
+	Package        string         `json:"package"`
+	Templates      []string       `json:"templates"`
+	Zone           string         `json:"zone"`
+	Version        float64        `json:"version"`
+	HAMode         float64        `json:"ha_mode"`
+	SourceLocation SourceLocation `json:"source_location"`
+
+	Groups       []string `json:"groups"`
+	Vars         Vars     `json:"vars"`
+	Notes        string   `json:"notes"`
+	NotesURL     string   `json:"notes_url"`
+	ActionURL    string   `json:"action_url"`
+	IconImage    string   `json:"icon_image"`
+	IconImageAlt string   `json:"icon_image_alt"`
+
+	State               float64   `json:"state"`
+	StateType           float64   `json:"state_type"`
+	LastState           float64   `json:"last_state"`
+	LastStateType       float64   `json:"last_state_type"`
+	LastHardState       float64   `json:"last_hard_state"`
+	LastReachable       bool      `json:"last_reachable"`
+	LastCheck           Timestamp `json:"last_check"`
+	NextCheck           Timestamp `json:"next_check"`
+	LastStateChange     Timestamp `json:"last_state_change"`
+	LastHardStateChange Timestamp `json:"last_hard_state_change"`
+	PreviousStateChange Timestamp `json:"previous_state_change"`
+	Handled             bool      `json:"handled"`
+	Problem             bool      `json:"problem"`
+	// Icinga2 computed severity, higher is worse
+	Severity float64 `json:"severity"`
+
+	CheckCommand     string   `json:"check_command"`
+	CheckPeriod      string   `json:"check_period"`
+	CheckInterval    float64  `json:"check_interval"`
+	RetryInterval    float64  `json:"retry_interval"`
+	CheckTimeout     *float64 `json:"check_timeout"`
+	CheckAttempt     float64  `json:"check_attempt"`
+	MaxCheckAttempts float64  `json:"max_check_attempts"`
+	CommandEndpoint  string   `json:"command_endpoint"`
+	EventCommand     string   `json:"event_command"`
+	Volatile         bool     `json:"volatile"`
+
+	EnableActiveChecks    bool `json:"enable_active_checks"`
+	EnablePassiveChecks   bool `json:"enable_passive_checks"`
+	EnableNotifications   bool `json:"enable_notifications"`
+	EnableEventHandler    bool `json:"enable_event_handler"`
+	EnableFlapping        bool `json:"enable_flapping"`
+	EnablePerfdata        bool `json:"enable_perfdata"`
+	ForceNextCheck        bool `json:"force_next_check"`
+	ForceNextNotification bool `json:"force_next_notification"`
+
+	Flapping              bool      `json:"flapping"`
+	FlappingCurrent       float64   `json:"flapping_current"`
+	FlappingLastChange    Timestamp `json:"flapping_last_change"`
+	FlappingThreshold     float64   `json:"flapping_threshold"`
+	FlappingThresholdHigh float64   `json:"flapping_threshold_high"`
+	FlappingThresholdLow  float64   `json:"flapping_threshold_low"`
+
+	DowntimeDepth         float64   `json:"downtime_depth"`
+	Acknowledgement       float64   `json:"acknowledgement"`
+	AcknowledgementExpiry Timestamp `json:"acknowledgement_expiry"`
+
+	LastCheckResult Icinga2APICheckResult `json:"last_check_result"`
+}
+
+// Host is Icinga2 host object with all its attributes
+type Host struct {
+	Checkable
+	Address              string    `json:"address"`
+	Address6             string    `json:"address6"`
+	LastStateUp          Timestamp `json:"last_state_up"`
+	LastStateDown        Timestamp `json:"last_state_down"`
+	LastStateUnreachable Timestamp `json:"last_state_unreachable"`
+}
+
+// Service is Icinga2 service object with all its attributes. Name is the short service name
+type Service struct {
+	Checkable
+	HostName             string    `json:"host_name"`
+	LastStateOK          Timestamp `json:"last_state_ok"`
+	LastStateWarning     Timestamp `json:"last_state_warning"`
+	LastStateCritical    Timestamp `json:"last_state_critical"`
+	LastStateUnknown     Timestamp `json:"last_state_unknown"`
+	LastStateUnreachable Timestamp `json:"last_state_unreachable"`
+	// host attributes requested by QueryOptions.Joins
+	JoinedHost *Host `json:"-"`
+}
+
+// url returns action_url if set, notes_url otherwise
+func (c *Checkable) url() string {
+	if len(c.ActionURL) > 0 {
+		return c.ActionURL
+	}
+	return c.NotesURL
+}
+
+func (c *Checkable) toCommon(state State) monitoring.Common {
+	var m monitoring.Common
+	m.State = state.State
+	m.StateHard = state.Hard
+	m.Timestamp = c.LastCheck.Time
+	m.LastStateChange = c.LastStateChange.Time
+	m.LastHardStateChange = c.LastHardStateChange.Time
+	m.Flapping = c.Flapping
+	m.CheckMessage = c.LastCheckResult.Message
+	m.DisplayName = c.DisplayName
+	m.Downtime = c.DowntimeDepth > 0
+	m.Acknowledged = c.Acknowledgement > 0
+	m.URL = c.url()
+	return m
+}
+
+// ToMonitoring converts host to the generic monitoring type
+func (h *Host) ToMonitoring() monitoring.Host {
+	return h.ToMonitoringWith(DefaultHostState)
+}
+
+// ToMonitoringWith converts host to the generic monitoring type with state mapped by f
+func (h *Host) ToMonitoringWith(f HostStateMapper) monitoring.Host {
+	return monitoring.Host{
+		Common: h.toCommon(f(h)),
+		Host:   h.Name,
+	}
+}
+
+// ToMonitoring converts service to the generic monitoring type
+func (s *Service) ToMonitoring() monitoring.Service {
+	return s.ToMonitoringWith(DefaultServiceState)
+}
+
+// ToMonitoringWith converts service to the generic monitoring type with state mapped by f
+func (s *Service) ToMonitoringWith(f ServiceStateMapper) monitoring.Service {
+	return monitoring.Service{
+		Common:  s.toCommon(f(s)),
+		Host:    s.HostName,
+		Service: s.Name,
+	}
+}
+
+func (i *Icinga2APIResponse) GetHostsDetailed() (v []Host) {
+	for _, obj := range i.Results {
+		if obj.Type != "Host" {
+			continue
+		}
+		var host Host
+		// assume reachable unless API says otherwise, so partial attribute lists don't make down hosts unreachable
+		host.LastReachable = true
+		err := json.Unmarshal(obj.Attrs, &host)
+		if err != nil {
+			log.Printf("error unmarshalling host %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		v = append(v, host)
+	}
+	return v
+}
+
+func (i *Icinga2APIResponse) GetServicesDetailed() (v []Service) {
+	for _, obj := range i.Results {
+		if obj.Type != "Service" {
+			continue
+		}
+		var service Service
+		service.LastReachable = true
+		err := json.Unmarshal(obj.Attrs, &service)
+		if err != nil {
+			log.Printf("error unmarshalling service %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		if joined, ok := obj.Joins["host"]; ok {
+			var host Host
+			host.LastReachable = true
+			if err := json.Unmarshal(joined, &host); err != nil {
+				log.Printf("error unmarshalling joined host of %s: %s | %s", obj.Name, err, string(joined))
+			} else {
+				service.JoinedHost = &host
+			}
+		}
+		v = append(v, service)
+	}
+	return v
+}
+
+// GetHostsDetailed returns hosts matching filter with all their attributes
+func (a *API) GetHostsDetailed(filter string) (m []Host, err error) {
+	return a.QueryHosts(QueryOptions{Filter: filter})
+}
+
+// GetServicesDetailed returns services matching filter with all their attributes
+func (a *API) GetServicesDetailed(filter string) (m []Service, err error) {
+	return a.QueryServices(QueryOptions{Filter: filter})
+}
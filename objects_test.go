@@ -0,0 +1,79 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAPI_GetHostsDetailed(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.host.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	hosts, err := Api.GetHostsDetailed(`host.name=="t1-host1"`)
+	require.Nil(t, err)
+	require.Len(t, hosts, 1)
+	h := hosts[0]
+	assert.Equal(t, "t1-host1", h.Name)
+	assert.Equal(t, "1.2.3.4", h.Address)
+	assert.Equal(t, []string{"debian-servers"}, h.Groups)
+	assert.Equal(t, "dc1", h.Vars["site"])
+	assert.Equal(t, "hostalive", h.CheckCommand)
+	assert.Equal(t, float64(3), h.MaxCheckAttempts)
+	assert.Equal(t, float64(1), h.CheckAttempt)
+	assert.Equal(t, float64(8), h.Severity)
+	assert.False(t, h.Handled)
+	assert.False(t, h.Problem)
+	assert.Equal(t, []string{"t1-host1", "generic-host"}, h.Templates)
+	assert.Equal(t, "/etc/icinga2/hosts/t1-host1.conf", h.SourceLocation.Path)
+	assert.Nil(t, h.CheckTimeout)
+	assert.Equal(t, int64(1619106554), h.NextCheck.Unix())
+	assert.True(t, h.AcknowledgementExpiry.IsZero())
+	assert.Equal(t, "t1-z2.example.com", h.LastCheckResult.CheckFrom)
+
+	m := h.ToMonitoring()
+	assert.Equal(t, "t1-host1", m.Host)
+	assert.Equal(t, monitoring.HostUp, int(m.State))
+	assert.True(t, m.StateHard)
+	assert.Equal(t, "https://grafana.example.com/dashboard/db/node?var-server=t1-host1", m.URL)
+	assert.Equal(t, "PING OK - Packet loss = 0%, RTA = 0.86 ms", m.CheckMessage)
+}
+
+func TestAPI_GetServicesDetailed(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Services", "v1.objects.services.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	services, err := Api.GetServicesDetailed("")
+	require.Nil(t, err)
+	require.Len(t, services, 7)
+	v := make(map[string]Service)
+	for _, s := range services {
+		v[s.HostName+"!"+s.Name] = s
+	}
+	s := v["t1-lb1!HTTPS example"]
+	assert.Equal(t, "t1-lb1", s.HostName)
+	assert.Equal(t, []string{"legacy", "http"}, s.Groups)
+	assert.False(t, s.LastStateOK.IsZero())
+	m := s.ToMonitoring()
+	assert.Equal(t, "t1-lb1", m.Host)
+	assert.Equal(t, "HTTPS example", m.Service)
+	assert.Equal(t, monitoring.StatusOk, int(m.State))
+}
+
+func TestTimestamp(t *testing.T) {
+	var ts Timestamp
+	require.Nil(t, json.Unmarshal([]byte("1619106496.5"), &ts))
+	assert.Equal(t, time.Unix(1619106496, 500000000), ts.Time)
+	out, err := json.Marshal(ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "1619106496.5", string(out))
+	require.Nil(t, json.Unmarshal([]byte("0"), &ts))
+	assert.True(t, ts.IsZero())
+	out, _ = json.Marshal(ts)
+	assert.Equal(t, "0", string(out))
+}
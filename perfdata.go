@@ -0,0 +1,398 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Perfdata is single performance data value as described in Nagios plugin guidelines:
+//
+//	'label'=value[UOM];[warn];[crit];[min];[max]
+//
+// Value, ranges and min/max are normalized to the base unit: seconds for time, bytes for size (kB=1000, KiB=1024)
+type Perfdata struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	// normalized unit: "s", "B", "%", "c" (counter) or as-is for unknown ones
+	Unit string `json:"unit,omitempty"`
+	// unit as returned by plugin
+	RawUnit string `json:"raw_unit,omitempty"`
+	// value was "U", plugin was unable to determine it. Value is 0 then
+	Undetermined bool     `json:"undetermined,omitempty"`
+	Warn         *Range   `json:"warn,omitempty"`
+	Crit         *Range   `json:"crit,omitempty"`
+	Min          *float64 `json:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty"`
+}
+
+// Range is warning/critical threshold range. Value outside of Start-End raises alert, or inside it if Inside is set (@ prefix)
+type Range struct {
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	Inside bool    `json:"inside,omitempty"`
+}
+
+// Alert returns whether value should raise alert for this range
+func (r *Range) Alert(v float64) bool {
+	in := v >= r.Start && v <= r.End
+	if r.Inside {
+		return in
+	}
+	return !in
+}
+
+var perfdataUnits = map[string]struct {
+	unit       string
+	multiplier float64
+}{
+	"":    {"", 1},
+	"%":   {"%", 1},
+	"c":   {"c", 1},
+	"s":   {"s", 1},
+	"ms":  {"s", 1e-3},
+	"us":  {"s", 1e-6},
+	"ns":  {"s", 1e-9},
+	"B":   {"B", 1},
+	"kB":  {"B", 1e3},
+	"KB":  {"B", 1e3},
+	"MB":  {"B", 1e6},
+	"GB":  {"B", 1e9},
+	"TB":  {"B", 1e12},
+	"PB":  {"B", 1e15},
+	"KiB": {"B", 1 << 10},
+	"MiB": {"B", 1 << 20},
+	"GiB": {"B", 1 << 30},
+	"TiB": {"B", 1 << 40},
+	"PiB": {"B", 1 << 50},
+}
+
+// ParsePerfdata parses space separated list of perfdata values.
+// On error it returns values parsed so far
+func ParsePerfdata(s string) (out []Perfdata, err error) {
+	out = make([]Perfdata, 0)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return out, nil
+		}
+		var item string
+		item, s, err = nextPerfdataItem(s)
+		if err != nil {
+			return out, err
+		}
+		p, err := ParsePerfdataValue(item)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, p)
+	}
+}
+
+// nextPerfdataItem splits first item off, respecting quoted labels which may contain spaces
+func nextPerfdataItem(s string) (item string, rest string, err error) {
+	i := 0
+	if s[0] == '\'' {
+		i = 1
+		for {
+			if i >= len(s) {
+				return "", "", fmt.Errorf("unterminated quote in perfdata [%s]", s)
+			}
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i += 2
+					continue
+				}
+				i++
+				break
+			}
+			i++
+		}
+	}
+	end := strings.IndexAny(s[i:], " \t\r\n")
+	if end < 0 {
+		return s, "", nil
+	}
+	return s[:i+end], s[i+end:], nil
+}
+
+// ParsePerfdataValue parses single perfdata value
+func ParsePerfdataValue(s string) (p Perfdata, err error) {
+	var rest string
+	if strings.HasPrefix(s, "'") {
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i++
+					continue
+				}
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return p, fmt.Errorf("unterminated quote in perfdata [%s]", s)
+		}
+		p.Label = strings.Replace(s[1:end], "''", "'", -1)
+		rest = s[end+1:]
+		if !strings.HasPrefix(rest, "=") {
+			return p, fmt.Errorf("missing = after label in perfdata [%s]", s)
+		}
+		rest = rest[1:]
+	} else {
+		eq := strings.LastIndex(s, "=")
+		if eq < 0 {
+			return p, fmt.Errorf("missing = in perfdata [%s]", s)
+		}
+		p.Label = s[:eq]
+		rest = s[eq+1:]
+	}
+	if p.Label == "" {
+		return p, fmt.Errorf("empty label in perfdata [%s]", s)
+	}
+	fields := strings.Split(rest, ";")
+	if len(fields) > 5 {
+		return p, fmt.Errorf("too many fields in perfdata [%s]", s)
+	}
+	value := fields[0]
+	numEnd := 0
+	for numEnd < len(value) && strings.IndexByte("0123456789.-+eE", value[numEnd]) >= 0 {
+		// do not treat "e" as exponent if there is no digit after it
+		if (value[numEnd] == 'e' || value[numEnd] == 'E') && (numEnd+1 >= len(value) || strings.IndexByte("0123456789-+", value[numEnd+1]) < 0) {
+			break
+		}
+		numEnd++
+	}
+	if value == "U" {
+		p.Undetermined = true
+	} else {
+		if numEnd == 0 {
+			return p, fmt.Errorf("no value in perfdata [%s]", s)
+		}
+		p.Value, err = strconv.ParseFloat(value[:numEnd], 64)
+		if err != nil {
+			return p, fmt.Errorf("bad value in perfdata [%s]: %s", s, err)
+		}
+		p.RawUnit = value[numEnd:]
+	}
+	unit, ok := perfdataUnits[p.RawUnit]
+	if !ok {
+		unit.unit, unit.multiplier = p.RawUnit, 1
+	}
+	p.Unit = unit.unit
+	p.Value *= unit.multiplier
+	if len(fields) > 1 && fields[1] != "" {
+		if p.Warn, err = ParseRange(fields[1]); err != nil {
+			return p, fmt.Errorf("bad warn range in perfdata [%s]: %s", s, err)
+		}
+		p.Warn.scale(unit.multiplier)
+	}
+	if len(fields) > 2 && fields[2] != "" {
+		if p.Crit, err = ParseRange(fields[2]); err != nil {
+			return p, fmt.Errorf("bad crit range in perfdata [%s]: %s", s, err)
+		}
+		p.Crit.scale(unit.multiplier)
+	}
+	if len(fields) > 3 && fields[3] != "" {
+		v, err := parsePerfdataNumber(fields[3])
+		if err != nil {
+			return p, fmt.Errorf("bad min in perfdata [%s]: %s", s, err)
+		}
+		v *= unit.multiplier
+		p.Min = &v
+	}
+	if len(fields) > 4 && fields[4] != "" {
+		v, err := parsePerfdataNumber(fields[4])
+		if err != nil {
+			return p, fmt.Errorf("bad max in perfdata [%s]: %s", s, err)
+		}
+		v *= unit.multiplier
+		p.Max = &v
+	}
+	if !p.finite() {
+		return p, fmt.Errorf("value out of range in perfdata [%s]", s)
+	}
+	return p, nil
+}
+
+// finite checks that unit normalization did not overflow any number
+func (p *Perfdata) finite() bool {
+	numbers := []float64{p.Value}
+	for _, r := range []*Range{p.Warn, p.Crit} {
+		if r != nil {
+			if !math.IsInf(r.Start, -1) {
+				numbers = append(numbers, r.Start)
+			}
+			if !math.IsInf(r.End, 1) {
+				numbers = append(numbers, r.End)
+			}
+		}
+	}
+	for _, f := range []*float64{p.Min, p.Max} {
+		if f != nil {
+			numbers = append(numbers, *f)
+		}
+	}
+	for _, f := range numbers {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseRange parses threshold range in [@][start:][end] format, "~" as start means negative infinity
+func ParseRange(s string) (*Range, error) {
+	r := &Range{Start: 0, End: math.Inf(1)}
+	if strings.HasPrefix(s, "@") {
+		r.Inside = true
+		s = s[1:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("empty range")
+	}
+	var err error
+	start, end := "", s
+	if i := strings.Index(s, ":"); i >= 0 {
+		start, end = s[:i], s[i+1:]
+	}
+	switch start {
+	case "":
+	case "~":
+		r.Start = math.Inf(-1)
+	default:
+		if r.Start, err = parsePerfdataNumber(start); err != nil {
+			return nil, err
+		}
+	}
+	if end != "" {
+		if r.End, err = parsePerfdataNumber(end); err != nil {
+			return nil, err
+		}
+	}
+	if r.Start > r.End {
+		return nil, fmt.Errorf("range start %v is greater than end %v", r.Start, r.End)
+	}
+	return r, nil
+}
+
+// String returns range in the perfdata format
+func (r Range) String() string {
+	var out string
+	if r.Inside {
+		out = "@"
+	}
+	switch {
+	case math.IsInf(r.Start, -1):
+		out += "~:"
+	case r.Start != 0:
+		out += strconv.FormatFloat(r.Start, 'f', -1, 64) + ":"
+	case math.IsInf(r.End, 1):
+		out += "0:"
+	}
+	if !math.IsInf(r.End, 1) {
+		out += strconv.FormatFloat(r.End, 'f', -1, 64)
+	}
+	return out
+}
+
+// MarshalJSON encodes range as a string, JSON can't represent infinite ends
+func (r Range) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Range) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseRange(s)
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// parsePerfdataNumber parses float, rejecting NaN and Inf that strconv accepts
+func parsePerfdataNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %s", s)
+	}
+	return f, nil
+}
+
+func (r *Range) scale(m float64) {
+	r.Start *= m
+	r.End *= m
+}
+
+// RawPerfdata is performance_data attribute of check result. API returns it as list of strings, this also accepts single string
+// and list of PerfdataValue objects
+type RawPerfdata []string
+
+func (r *RawPerfdata) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*r = RawPerfdata{single}
+		return nil
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	out := make(RawPerfdata, 0, len(list))
+	for _, item := range list {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			out = append(out, s)
+			continue
+		}
+		var v struct {
+			Label string   `json:"label"`
+			Value float64  `json:"value"`
+			Unit  string   `json:"unit"`
+			Warn  *float64 `json:"warn"`
+			Crit  *float64 `json:"crit"`
+			Min   *float64 `json:"min"`
+			Max   *float64 `json:"max"`
+		}
+		if err := json.Unmarshal(item, &v); err != nil {
+			return fmt.Errorf("unknown perfdata format %s: %s", string(item), err)
+		}
+		parts := []string{"'" + strings.Replace(v.Label, "'", "''", -1) + "'=" + formatFloat(&v.Value) + v.Unit}
+		for _, f := range []*float64{v.Warn, v.Crit, v.Min, v.Max} {
+			parts = append(parts, formatFloat(f))
+		}
+		out = append(out, strings.TrimRight(strings.Join(parts, ";"), ";"))
+	}
+	*r = out
+	return nil
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+// Parse parses all values. On error it returns values parsed so far
+func (r RawPerfdata) Parse() ([]Perfdata, error) {
+	out := make([]Perfdata, 0, len(r))
+	for _, s := range r {
+		p, err := ParsePerfdata(s)
+		out = append(out, p...)
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,185 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParsePerfdataValue(t *testing.T) {
+	p, err := ParsePerfdataValue("rta=0.855000ms;3000.000000;5000.000000;0.000000")
+	require.Nil(t, err)
+	assert.Equal(t, "rta", p.Label)
+	assert.InDelta(t, 0.000855, p.Value, 1e-12)
+	assert.Equal(t, "s", p.Unit)
+	assert.Equal(t, "ms", p.RawUnit)
+	assert.InDelta(t, 3.0, p.Warn.End, 1e-9)
+	assert.InDelta(t, 5.0, p.Crit.End, 1e-9)
+	assert.Equal(t, 0.0, *p.Min)
+	assert.Nil(t, p.Max)
+
+	p, err = ParsePerfdataValue("pl=0%;80;100;0")
+	require.Nil(t, err)
+	assert.Equal(t, "%", p.Unit)
+	assert.Equal(t, 80.0, p.Warn.End)
+
+	p, err = ParsePerfdataValue("'disk /var''s free'=2KiB;;;0;4096")
+	require.Nil(t, err)
+	assert.Equal(t, "disk /var's free", p.Label)
+	assert.Equal(t, 2048.0, p.Value)
+	assert.Equal(t, "B", p.Unit)
+	assert.Nil(t, p.Warn)
+	assert.Equal(t, 4096.0*1024, *p.Max)
+
+	p, err = ParsePerfdataValue("users=U;;")
+	require.Nil(t, err)
+	assert.True(t, p.Undetermined)
+
+	p, err = ParsePerfdataValue("requests=1234c")
+	require.Nil(t, err)
+	assert.Equal(t, "c", p.Unit)
+	assert.Equal(t, 1234.0, p.Value)
+
+	p, err = ParsePerfdataValue("load=1.5e2;~:10;@5:20")
+	require.Nil(t, err)
+	assert.Equal(t, 150.0, p.Value)
+	assert.True(t, math.IsInf(p.Warn.Start, -1))
+	assert.True(t, p.Crit.Inside)
+}
+
+func TestParsePerfdataValue_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"=1",
+		"label",
+		"label=",
+		"label=abc",
+		"'label=1",
+		"'label'1",
+		"label=1;2;3;4;5;6",
+		"label=1;20:10",
+		"label=1;;;NaN",
+		"label=1;@",
+		"label=1e308PB",
+	} {
+		_, err := ParsePerfdataValue(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParsePerfdata(t *testing.T) {
+	p, err := ParsePerfdata("time=0.007513s;;;0.000000;30.000000 'used space'=10MB;80;90 size=166B;;;0")
+	require.Nil(t, err)
+	require.Len(t, p, 3)
+	assert.Equal(t, "used space", p[1].Label)
+	assert.Equal(t, 10e6, p[1].Value)
+	assert.Equal(t, 80e6, p[1].Warn.End)
+	assert.Equal(t, "size", p[2].Label)
+
+	p, err = ParsePerfdata("a=1 b=x c=3")
+	assert.Error(t, err)
+	assert.Len(t, p, 1, "returns values parsed before error")
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		in     string
+		out    string
+		alerts []float64
+		ok     []float64
+	}{
+		{"10", "10", []float64{-1, 11}, []float64{0, 5, 10}},
+		{"10:", "10:", []float64{9}, []float64{10, 1e9}},
+		{"~:10", "~:10", []float64{11}, []float64{-1e9, 10}},
+		{"10:20", "10:20", []float64{9, 21}, []float64{10, 20}},
+		{"@10:20", "@10:20", []float64{10, 15, 20}, []float64{9, 21}},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.in)
+		require.Nil(t, err, tt.in)
+		assert.Equal(t, tt.out, r.String())
+		for _, v := range tt.alerts {
+			assert.True(t, r.Alert(v), "%s should alert on %v", tt.in, v)
+		}
+		for _, v := range tt.ok {
+			assert.False(t, r.Alert(v), "%s should not alert on %v", tt.in, v)
+		}
+	}
+}
+
+func TestRawPerfdata(t *testing.T) {
+	var c Icinga2APICheckResult
+	err := json.Unmarshal([]byte(`{"performance_data":["rta=0.855000ms;3000.000000;5000.000000;0.000000","pl=0%;80;100;0"]}`), &c)
+	require.Nil(t, err)
+	p, err := c.Perfdata()
+	require.Nil(t, err)
+	assert.Len(t, p, 2)
+
+	err = json.Unmarshal([]byte(`{"performance_data":"a=1 b=2"}`), &c)
+	require.Nil(t, err)
+	p, err = c.Perfdata()
+	require.Nil(t, err)
+	assert.Len(t, p, 2)
+
+	err = json.Unmarshal([]byte(`{"performance_data":[{"type":"PerfdataValue","label":"it's","value":1.5,"unit":"ms","warn":2,"crit":null,"min":0,"max":null}]}`), &c)
+	require.Nil(t, err)
+	p, err = c.Perfdata()
+	require.Nil(t, err)
+	require.Len(t, p, 1)
+	assert.Equal(t, "it's", p[0].Label)
+	assert.Equal(t, 0.002, p[0].Warn.End)
+
+	err = json.Unmarshal([]byte(`{"output":"OK","performance_data":[{"label":[]}]}`), &c)
+	require.Nil(t, err, "unknown perfdata format should not fail whole check result")
+	assert.Equal(t, "OK", c.Message)
+	_, err = c.Perfdata()
+	assert.Error(t, err)
+
+	err = json.Unmarshal([]byte(`{"performance_data":["a=1"]}`), &c)
+	require.Nil(t, err)
+	p, err = c.Perfdata()
+	require.Nil(t, err, "error should be reset on next decode")
+	assert.Len(t, p, 1)
+}
+
+func FuzzParsePerfdata(f *testing.F) {
+	for _, s := range []string{
+		"rta=0.855000ms;3000.000000;5000.000000;0.000000",
+		"pl=0%;80;100;0",
+		"time=0.007513s;;;0.000000;30.000000 size=166B;;;0",
+		"'quoted '' label'=1KiB;@10:20;~:30;0;100",
+		"users=U;;",
+		"a=1e5c",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		values, err := ParsePerfdata(s)
+		if err != nil {
+			return
+		}
+		for _, p := range values {
+			if p.Label == "" {
+				t.Errorf("empty label parsed from %q", s)
+			}
+			if !p.finite() {
+				t.Errorf("non-finite value parsed from %q", s)
+			}
+			if p.Warn != nil {
+				r, err := ParseRange(p.Warn.String())
+				if err != nil || r.Inside != p.Warn.Inside {
+					t.Errorf("warn range %+v does not round-trip: %v", p.Warn, err)
+				}
+			}
+			if _, err := json.Marshal(p); err != nil {
+				t.Errorf("can't marshal %+v from %q: %s", p, s, err)
+			}
+		}
+		if !strings.ContainsAny(s, "=") && len(values) > 0 {
+			t.Errorf("parsed values from %q without =", s)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-monitoring"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProblemOptions selects problems returned by Problems
+type ProblemOptions struct {
+	// additional filter applied to both hosts and services, e.g. `"linux" in host.groups`
+	Filter string
+	// skip acknowledged, downtimed and otherwise handled problems
+	UnhandledOnly bool
+	// skip problems in soft state
+	HardOnly bool
+	// return only problems with listed monitoring states, all non-OK states if empty
+	States []uint8
+	// skip host or service problems
+	NoHosts    bool
+	NoServices bool
+}
+
+// Problem is host or service in non-OK state
+type Problem struct {
+	// set by Proxy
+	Server string `json:"server,omitempty"`
+	Host   string `json:"host"`
+	// empty for host problems
+	Service      string `json:"service,omitempty"`
+	DisplayName  string `json:"display_name"`
+	State        uint8  `json:"state"`
+	Hard         bool   `json:"hard"`
+	Handled      bool   `json:"handled"`
+	Acknowledged bool   `json:"acknowledged"`
+	Downtime     bool   `json:"downtime"`
+	// Icinga2 severity, bigger is more important
+	Severity float64 `json:"severity"`
+	// time of the last state change and time spent in the state
+	Since    time.Time     `json:"since"`
+	Duration time.Duration `json:"duration"`
+	Message  string        `json:"message"`
+	URL      string        `json:"url"`
+}
+
+// IsHost returns true for host problems
+func (p *Problem) IsHost() bool {
+	return p.Service == ""
+}
+
+// Problems is list of problems ordered by severity
+type Problems []Problem
+
+// HostProblems are problems of single host
+type HostProblems struct {
+	Server string `json:"server,omitempty"`
+	Host   string `json:"host"`
+	// nil if host itself is UP
+	HostProblem *Problem  `json:"host_problem"`
+	Services    []Problem `json:"services"`
+	// highest severity of host and its services
+	Severity float64 `json:"severity"`
+}
+
+// Unhandled returns problems that are not handled
+func (p Problems) Unhandled() Problems {
+	out := make(Problems, 0, len(p))
+	for _, problem := range p {
+		if !problem.Handled {
+			out = append(out, problem)
+		}
+	}
+	return out
+}
+
+// ByHost groups problems by host (and server), hosts are ordered by their highest severity
+func (p Problems) ByHost() []HostProblems {
+	idx := make(map[[2]string]int)
+	out := make([]HostProblems, 0)
+	for i := range p {
+		problem := p[i]
+		key := [2]string{problem.Server, problem.Host}
+		n, ok := idx[key]
+		if !ok {
+			n = len(out)
+			idx[key] = n
+			out = append(out, HostProblems{Server: problem.Server, Host: problem.Host, Services: []Problem{}})
+		}
+		if problem.IsHost() {
+			out[n].HostProblem = &problem
+		} else {
+			out[n].Services = append(out[n].Services, problem)
+		}
+		if problem.Severity > out[n].Severity {
+			out[n].Severity = problem.Severity
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Severity > out[j].Severity })
+	return out
+}
+
+func (p Problems) sort() {
+	sort.SliceStable(p, func(i, j int) bool {
+		if p[i].Severity != p[j].Severity {
+			return p[i].Severity > p[j].Severity
+		}
+		if !p[i].Since.Equal(p[j].Since) {
+			return p[i].Since.Before(p[j].Since)
+		}
+		if p[i].Host != p[j].Host {
+			return p[i].Host < p[j].Host
+		}
+		if p[i].Service != p[j].Service {
+			return p[i].Service < p[j].Service
+		}
+		return p[i].Server < p[j].Server
+	})
+}
+
+func (o *ProblemOptions) filter(objType string) string {
+	filters := []Filter{NotEq(objType+".state", 0)}
+	if o.HardOnly {
+		filters = append(filters, Eq(objType+".state_type", 1))
+	}
+	if o.Filter != "" {
+		filters = append(filters, RawFilter(o.Filter))
+	}
+	return And(filters...).String()
+}
+
+func (o *ProblemOptions) match(p *Problem) bool {
+	if o.UnhandledOnly && p.Handled {
+		return false
+	}
+	if o.HardOnly && !p.Hard {
+		return false
+	}
+	if len(o.States) == 0 {
+		return true
+	}
+	for _, s := range o.States {
+		if s == p.State {
+			return true
+		}
+	}
+	return false
+}
+
+func newProblem(c *Checkable, common monitoring.Common, state State, now time.Time) Problem {
+	p := Problem{
+		DisplayName:  common.DisplayName,
+		State:        state.State,
+		Hard:         state.Hard,
+		Handled:      state.Handled,
+		Acknowledged: common.Acknowledged,
+		Downtime:     common.Downtime,
+		Severity:     c.Severity,
+		Since:        c.LastStateChange.Time,
+		Message:      common.CheckMessage,
+		URL:          common.URL,
+	}
+	if !p.Since.IsZero() {
+		p.Duration = now.Sub(p.Since)
+	}
+	return p
+}
+
+// Problems returns hosts and services in non-OK state, ordered by severity (the highest first) and then by duration (the longest first)
+func (a *API) Problems(opts ProblemOptions) (p Problems, err error) {
+	p = make(Problems, 0)
+	now := time.Now()
+	if !opts.NoHosts {
+		hosts, err := a.QueryHosts(QueryOptions{
+			Filter: opts.filter("host"),
+			Attrs:  append([]string{"severity"}, HostMonitoringAttrs...),
+		})
+		if err != nil {
+			return p, err
+		}
+		for i := range hosts {
+			h := &hosts[i]
+			state := a.hostState()(h)
+			problem := newProblem(&h.Checkable, h.toCommon(state), state, now)
+			problem.Host = h.Name
+			if opts.match(&problem) {
+				p = append(p, problem)
+			}
+		}
+	}
+	if !opts.NoServices {
+		services, err := a.QueryServices(QueryOptions{
+			Filter: opts.filter("service"),
+			Attrs:  append([]string{"severity"}, ServiceMonitoringAttrs...),
+		})
+		if err != nil {
+			return p, err
+		}
+		for i := range services {
+			s := &services[i]
+			state := a.serviceState()(s)
+			problem := newProblem(&s.Checkable, s.toCommon(state), state, now)
+			problem.Host = s.HostName
+			problem.Service = s.Name
+			if opts.match(&problem) {
+				p = append(p, problem)
+			}
+		}
+	}
+	p.sort()
+	return p, nil
+}
+
+// Problems returns problems from all servers with Server set, ordered like API.Problems
+func (a *Proxy) Problems(opts ProblemOptions) (p Problems, err error) {
+	var lock sync.Mutex
+	p = make(Problems, 0)
+	errs := a.each(func(name string, s *API) error {
+		problems, err := s.Problems(opts)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, problem := range problems {
+			problem.Server = name
+			p = append(p, problem)
+		}
+		return nil
+	})
+	p.sort()
+	return p, proxyError(errs)
+}
@@ -0,0 +1,90 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var problemFiles = map[string]string{
+	"/v1/objects/Hosts":    "v1.objects.problems_hosts.json",
+	"/v1/objects/Services": "v1.objects.problems_services.json",
+}
+
+func TestAPI_Problems(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", problemFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	p, err := Api.Problems(ProblemOptions{})
+	require.Nil(t, err)
+	require.Len(t, p, 5)
+	names := make([]string, 0)
+	for _, problem := range p {
+		if problem.IsHost() {
+			names = append(names, problem.Host)
+		} else {
+			names = append(names, problem.Host+"!"+problem.Service)
+		}
+	}
+	assert.Equal(t, []string{"t1-db1", "t1-web1!HTTP", "t1-web1!DISK", "t1-db1!POSTGRES", "t1-db2"}, names, "ordered by severity")
+	assert.Equal(t, uint8(monitoring.HostDown), p[0].State)
+	assert.True(t, p[0].Duration > 0)
+	assert.Equal(t, "PING CRITICAL - Packet loss = 100%", p[0].Message)
+	assert.True(t, p[3].Handled)
+	assert.True(t, p[3].Acknowledged)
+	assert.True(t, p[4].Downtime)
+	assert.Len(t, p.Unhandled(), 3)
+
+	byHost := p.ByHost()
+	require.Len(t, byHost, 3)
+	assert.Equal(t, "t1-db1", byHost[0].Host)
+	require.NotNil(t, byHost[0].HostProblem)
+	assert.Len(t, byHost[0].Services, 1)
+	assert.Equal(t, "t1-web1", byHost[1].Host)
+	assert.Nil(t, byHost[1].HostProblem)
+	assert.Len(t, byHost[1].Services, 2)
+}
+
+func TestAPI_Problems_Options(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", problemFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	p, err := Api.Problems(ProblemOptions{
+		UnhandledOnly: true,
+		HardOnly:      true,
+		NoHosts:       true,
+		States:        []uint8{monitoring.StatusCritical},
+	})
+	require.Nil(t, err)
+	require.Len(t, p, 1)
+	assert.Equal(t, "HTTP", p[0].Service)
+}
+
+func TestProblemOptions_filter(t *testing.T) {
+	o := ProblemOptions{HardOnly: true, Filter: `"linux" in host.groups`}
+	assert.Equal(t, `(service.state!=0) && (service.state_type==1) && ("linux" in host.groups)`, o.filter("service"))
+	assert.Equal(t, `host.state!=0`, (&ProblemOptions{}).filter("host"))
+}
+
+func TestProxy_Problems(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", problemFiles)
+	ts2 := icingatest.NewServer(t, "testdata", problemFiles)
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	problems, err := p.Problems(ProblemOptions{UnhandledOnly: true})
+	require.Nil(t, err)
+	require.Len(t, problems, 6)
+	assert.Equal(t, "s1", problems[0].Server)
+	assert.Equal(t, "s2", problems[1].Server)
+	assert.Len(t, problems.ByHost(), 4, "hosts are grouped per server")
+}
@@ -0,0 +1,106 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-monitoring"
+	"net/url"
+)
+
+// HostMonitoringAttrs are host attributes needed to map it into monitoring.Host
+var HostMonitoringAttrs = []string{
+	"name", "display_name", "state", "state_type", "last_reachable", "handled",
+	"last_check", "last_state_change", "last_hard_state_change", "last_check_result",
+	"flapping", "downtime_depth", "acknowledgement", "action_url", "notes_url",
+}
+
+// ServiceMonitoringAttrs are service attributes needed to map it into monitoring.Service
+var ServiceMonitoringAttrs = append([]string{"host_name"}, HostMonitoringAttrs...)
+
+// QueryOptions selects which objects and which of their attributes are returned
+type QueryOptions struct {
+	Filter string
+	// attributes to return, all if empty. Attributes needed for monitoring type mapping are always added
+	Attrs []string
+	// attributes of joined objects, "host" returns all host attributes of a service, "host.state" only one of them
+	Joins []string
+}
+
+func (o QueryOptions) query() url.Values {
+	q := url.Values{}
+	if o.Filter != "" {
+		q.Set("filter", o.Filter)
+	}
+	for _, a := range o.Attrs {
+		q.Add("attrs", a)
+	}
+	for _, j := range o.Joins {
+		q.Add("joins", j)
+	}
+	return q
+}
+
+// withAttrs returns copy of options with required attributes added, unless all attributes were requested
+func (o QueryOptions) withAttrs(required []string) QueryOptions {
+	if len(o.Attrs) == 0 {
+		return o
+	}
+	seen := make(map[string]bool, len(o.Attrs)+len(required))
+	attrs := make([]string, 0, len(o.Attrs)+len(required))
+	for _, list := range [][]string{required, o.Attrs} {
+		for _, a := range list {
+			if !seen[a] {
+				seen[a] = true
+				attrs = append(attrs, a)
+			}
+		}
+	}
+	o.Attrs = attrs
+	return o
+}
+
+// QueryHosts returns hosts selected by options. Attributes that were not requested are left empty
+func (a *API) QueryHosts(opts QueryOptions) (m []Host, err error) {
+	i, err := a.getObjects("Hosts", opts.withAttrs(HostMonitoringAttrs))
+	if err != nil {
+		return m, err
+	}
+	return i.GetHostsDetailed(), nil
+}
+
+// QueryServices returns services selected by options. Joined host attributes are available in JoinedHost
+func (a *API) QueryServices(opts QueryOptions) (m []Service, err error) {
+	i, err := a.getObjects("Services", opts.withAttrs(ServiceMonitoringAttrs))
+	if err != nil {
+		return m, err
+	}
+	return i.GetServicesDetailed(), nil
+}
+
+// GetHostsWithOptions returns hosts selected by options, fetching only attributes needed for mapping unless more are requested
+func (a *API) GetHostsWithOptions(opts QueryOptions) (m []monitoring.Host, err error) {
+	if len(opts.Attrs) == 0 {
+		opts.Attrs = HostMonitoringAttrs
+	}
+	hosts, err := a.QueryHosts(opts)
+	if err != nil {
+		return m, err
+	}
+	for _, h := range hosts {
+		m = append(m, h.ToMonitoringWith(a.hostState()))
+	}
+	return m, nil
+}
+
+// GetServicesWithOptions returns services selected by options, fetching only attributes needed for mapping unless more are requested
+func (a *API) GetServicesWithOptions(opts QueryOptions) (m []monitoring.Service, err error) {
+	if len(opts.Attrs) == 0 {
+		opts.Attrs = ServiceMonitoringAttrs
+	}
+	services, err := a.QueryServices(opts)
+	if err != nil {
+		return m, err
+	}
+	for _, s := range services {
+		m = append(m, s.ToMonitoringWith(a.serviceState()))
+	}
+	return m, nil
+}
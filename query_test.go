@@ -0,0 +1,86 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_GetHostsWithOptions(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "v1.objects.hosts.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	hosts, err := Api.GetHostsWithOptions(QueryOptions{Filter: `host.state==0`})
+	require.Nil(t, err)
+	assert.Len(t, hosts, 7)
+	assert.Equal(t, `host.state==0`, ts.LastQuery().Get("filter"))
+	assert.ElementsMatch(t, HostMonitoringAttrs, ts.LastQuery()["attrs"], "minimal set by default")
+	assert.Equal(t, monitoring.HostUp, int(hosts[0].State))
+}
+
+func TestAPI_QueryHosts_Attrs(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "v1.objects.host.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.QueryHosts(QueryOptions{Attrs: []string{"address", "state"}})
+	require.Nil(t, err)
+	assert.Contains(t, ts.LastQuery()["attrs"], "address")
+	assert.Contains(t, ts.LastQuery()["attrs"], "last_check_result", "required attribute added")
+	assert.Len(t, ts.LastQuery()["attrs"], len(HostMonitoringAttrs)+1, "no duplicates")
+
+	_, err = Api.QueryHosts(QueryOptions{})
+	require.Nil(t, err)
+	assert.Empty(t, ts.LastQuery()["attrs"], "all attributes")
+}
+
+func TestAPI_QueryServices_Joins(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Services": "v1.objects.services_joins.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	services, err := Api.QueryServices(QueryOptions{
+		Attrs: []string{"state"},
+		Joins: []string{"host.name", "host.state", "host.address"},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"host.name", "host.state", "host.address"}, ts.LastQuery()["joins"])
+	assert.Contains(t, ts.LastQuery()["attrs"], "host_name")
+	require.Len(t, services, 2)
+	require.NotNil(t, services[0].JoinedHost)
+	assert.Equal(t, "t1-host1", services[0].JoinedHost.Name)
+	assert.Equal(t, "1.2.3.4", services[0].JoinedHost.Address)
+	assert.Equal(t, float64(1), services[0].JoinedHost.State)
+
+	ms, err := Api.GetServicesWithOptions(QueryOptions{})
+	require.Nil(t, err)
+	assert.Len(t, ms, 2)
+	assert.ElementsMatch(t, ServiceMonitoringAttrs, ts.LastQuery()["attrs"])
+}
+
+func TestAPI_QueryObjects(t *testing.T) {
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "v1.objects.hosts.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	i, err := Api.QueryObjects("Hosts", QueryOptions{Filter: `host.name=="t1-host1"`, Attrs: []string{"address"}})
+	require.Nil(t, err)
+	assert.Len(t, i.Results, 7)
+	assert.Equal(t, "Host", i.Results[0].Type)
+	assert.Equal(t, []string{"address"}, ts.LastQuery()["attrs"], "required attributes are not added to raw query")
+
+	ts2 := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "error.no-objects-found.json"})
+	defer ts2.Close()
+	Api, err = New(ts2.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.QueryObjects("Hosts", QueryOptions{})
+	apiErr, ok := err.(*APIError)
+	require.True(t, ok, "%s", err)
+	assert.Equal(t, 404, apiErr.Code)
+}
@@ -0,0 +1,178 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// APIError is request-level error returned by Icinga2, e.g. 404 when filter matched no objects
+type APIError struct {
+	Code   int
+	Status string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("[%d] %s", e.Code, e.Status)
+}
+
+// ActionResult is result of action on single object
+type ActionResult struct {
+	// set by Proxy
+	Server string `json:"server,omitempty"`
+	// host or "host!service" the action was executed on
+	Object string `json:"object"`
+	// name of created downtime or comment
+	Name     string `json:"name,omitempty"`
+	Code     int    `json:"code"`
+	Status   string `json:"status"`
+	LegacyID int    `json:"legacy_id,omitempty"`
+	// set for objects that were only matched in dry run
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Succeeded returns true for successfully executed action
+func (r *ActionResult) Succeeded() bool {
+	return r.Code >= 200 && r.Code < 300
+}
+
+// Failed returns true if action failed for the object
+func (r *ActionResult) Failed() bool {
+	return r.Code >= 300
+}
+
+// ActionResults is result of action on all matched objects
+type ActionResults []ActionResult
+
+// Succeeded returns successful results
+func (r ActionResults) Succeeded() ActionResults {
+	out := make(ActionResults, 0, len(r))
+	for _, res := range r {
+		if res.Succeeded() {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// Failed returns results of objects action failed for
+func (r ActionResults) Failed() ActionResults {
+	out := make(ActionResults, 0)
+	for _, res := range r {
+		if res.Failed() {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// Objects returns sorted, deduplicated names of objects
+func (r ActionResults) Objects() []string {
+	seen := make(map[string]bool, len(r))
+	out := make([]string, 0, len(r))
+	for _, res := range r {
+		if res.Object != "" && !seen[res.Object] {
+			seen[res.Object] = true
+			out = append(out, res.Object)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Names returns names of created downtimes or comments
+func (r ActionResults) Names() []string {
+	out := make([]string, 0, len(r))
+	for _, res := range r {
+		if res.Name != "" {
+			out = append(out, res.Name)
+		}
+	}
+	return out
+}
+
+// Err returns error describing all failures, nil if there were none
+func (r ActionResults) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(failed))
+	for _, res := range failed {
+		msg := fmt.Sprintf("[%d] %s", res.Code, res.Status)
+		if res.Object != "" {
+			msg = res.Object + ": " + msg
+		}
+		if res.Server != "" {
+			msg = res.Server + "/" + msg
+		}
+		msgs = append(msgs, msg)
+	}
+	return fmt.Errorf("action failed for %d of %d objects: %s", len(failed), len(r), strings.Join(msgs, "; "))
+}
+
+func dryRunResults(objects []string) ActionResults {
+	out := make(ActionResults, 0, len(objects))
+	for _, o := range objects {
+		out = append(out, ActionResult{Object: o, Status: "dry run", DryRun: true})
+	}
+	return out
+}
+
+var statusObjectRe = regexp.MustCompile(`for object '([^']+)'`)
+
+// GetActionResults converts action response into per-object results
+func (i *Icinga2StatusResponseOk) GetActionResults() ActionResults {
+	out := make(ActionResults, 0, len(i.Results))
+	for _, obj := range i.Results {
+		res := ActionResult{
+			Name:     obj.Name,
+			Code:     int(obj.Code),
+			Status:   obj.Status,
+			LegacyID: int(obj.LegacyID),
+		}
+		if len(obj.Errors) > 0 {
+			res.Status += ": " + strings.Join(obj.Errors, "; ")
+		}
+		// created object is named "host!uuid" or "host!service!uuid"
+		if n := strings.LastIndex(obj.Name, "!"); n > 0 {
+			res.Object = obj.Name[:n]
+		} else if m := statusObjectRe.FindStringSubmatch(obj.Status); m != nil {
+			res.Object = m[1]
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+// ExecuteAction posts data as-is to /v1/actions/<action>, for actions and parameters without dedicated method.
+// Request-level errors (e.g. no objects matched) are returned as *APIError
+func (a *API) ExecuteAction(action string, data interface{}) (ActionResults, error) {
+	return a.postAction(action, data)
+}
+
+// postAction executes /v1/actions/<action>. Request-level errors are returned as *APIError
+func (a *API) postAction(action string, data interface{}) (ActionResults, error) {
+	body, err := a.request("POST", "/v1/actions/"+action, data)
+	if err != nil {
+		return nil, err
+	}
+	var i Icinga2StatusResponseOk
+	err = json.Unmarshal(body, &i)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+	}
+	if len(i.Results) == 0 {
+		var e Icinga2StatusPart
+		err = json.Unmarshal(body, &e)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+		}
+		if e.Error != 0 {
+			return nil, &APIError{Code: int(e.Error), Status: e.Status}
+		}
+	}
+	return i.GetActionResults(), nil
+}
@@ -0,0 +1,103 @@
+package icinga2
+
+import (
+	"context"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var partialDowntimeFiles = map[string]string{
+	"/v1/actions/schedule-downtime": "v1.actions.schedule-downtime.partial.json",
+	"/v1/actions/remove-downtime":   "v1.actions.remove-downtime.json",
+}
+
+func TestAPI_ScheduleHostDowntime_Results(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", partialDowntimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	results, err := Api.ScheduleHostDowntimeByFilterWithOptions(`match("t1-mon*", host.name)`, testDowntime(), ActionOptions{})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, ActionResult{
+		Object:   "t1-mon1",
+		Name:     "t1-mon1!2316b99e-c70c-41ab-aaca-684fab53fa24",
+		Code:     200,
+		Status:   "Successfully scheduled downtime 't1-mon1!2316b99e-c70c-41ab-aaca-684fab53fa24' for object 't1-mon1'.",
+		LegacyID: 26,
+	}, results[0])
+	assert.Equal(t, "t1-mon2", results[1].Object, "object is parsed from status if there is no name")
+	assert.True(t, results[1].Failed())
+	assert.Len(t, results.Succeeded(), 1)
+	assert.EqualError(t, results.Err(), "action failed for 1 of 2 objects: t1-mon2: [500] Could not schedule downtime for object 't1-mon2'.: Error: Object 't1-mon2' is not authoritative in this zone.")
+
+	hosts, err := Api.ScheduleHostDowntimeByFilter(`match("t1-mon*", host.name)`, testDowntime())
+	require.Nil(t, err)
+	assert.Equal(t, []string{"t1-mon1"}, hosts, "legacy method returns only successes")
+}
+
+func TestAPI_WithDowntime_PartialFailure(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", partialDowntimeFiles)
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	err = Api.WithDowntime(context.Background(), HostsTarget("true"), testDowntime(), func(ctx context.Context) error {
+		t.Error("should not run if some objects are not in downtime")
+		return nil
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "t1-mon2")
+	assert.Equal(t, []string{"t1-mon1!2316b99e-c70c-41ab-aaca-684fab53fa24"}, removedDowntimes(t, ts.Requests()), "created downtimes are removed")
+}
+
+func TestProxy_ScheduleHostDowntime_Results(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", partialDowntimeFiles)
+	ts2 := icingatest.NewServer(t, "testdata", downtimeFiles)
+	defer ts.Close()
+	defer ts2.Close()
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	results, err := p.ScheduleHostDowntimeByFilterWithOptions(`match("t1-mon*", host.name)`, testDowntime(), ActionOptions{})
+	require.Nil(t, err)
+	require.Len(t, results, 4)
+	assert.Equal(t, "s1", results[0].Server)
+	assert.Equal(t, "s2", results[3].Server)
+	failed := results.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "s1", failed[0].Server)
+	assert.Contains(t, results.Err().Error(), "s1/t1-mon2: [500]")
+}
+
+func TestAPI_postAction_Error(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/actions/remove-downtime", "error.no-objects-found.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.postAction("remove-downtime", map[string]string{"type": "Downtime", "filter": "false"})
+	require.NotNil(t, err)
+	apiErr, ok := err.(*APIError)
+	require.True(t, ok)
+	assert.Equal(t, 404, apiErr.Code)
+	assert.Equal(t, "[404] No objects found.", err.Error())
+}
+
+func TestAPI_ExecuteAction(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/actions/schedule-downtime", "v1.actions.schedule-downtime.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	results, err := Api.ExecuteAction("schedule-downtime", map[string]interface{}{"type": "Host", "filter": "true", "child_options": "DowntimeTriggeredChildren"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"t1-mon1", "t1-mon2"}, results.Objects())
+	assert.Contains(t, ts.LastBody(), `"child_options":"DowntimeTriggeredChildren"`)
+}
@@ -0,0 +1,282 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChildOptions controls downtimes of child hosts and services
+type ChildOptions string
+
+const (
+	DowntimeNoChildren           ChildOptions = "DowntimeNoChildren"
+	DowntimeTriggeredChildren    ChildOptions = "DowntimeTriggeredChildren"
+	DowntimeNonTriggeredChildren ChildOptions = "DowntimeNonTriggeredChildren"
+)
+
+// DowntimeRanges maps Icinga2 day specification to comma separated time ranges,
+// e.g. {"sunday": "02:00-04:00", "monday 1 january": "00:00-24:00", "day 1 - 7 / 2": "10:00-11:00,15:00-16:00"}
+type DowntimeRanges map[string]string
+
+var (
+	weekdays = map[string]bool{
+		"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+	}
+	months = map[string]bool{
+		"january": true, "february": true, "march": true, "april": true, "may": true, "june": true,
+		"july": true, "august": true, "september": true, "october": true, "november": true, "december": true,
+	}
+	rangeDateRe   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	rangeNumberRe = regexp.MustCompile(`^-?\d+$`)
+	rangeTimeRe   = regexp.MustCompile(`^(\d{1,2}):(\d{2})-(\d{1,2}):(\d{2})$`)
+)
+
+// Validate checks syntax of all ranges
+func (r DowntimeRanges) Validate() error {
+	if len(r) == 0 {
+		return fmt.Errorf("no ranges")
+	}
+	days := make([]string, 0, len(r))
+	for day := range r {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days {
+		err := validateRangeDay(day)
+		if err != nil {
+			return fmt.Errorf("invalid range day [%s]: %s", day, err)
+		}
+		err = validateRangeTimes(r[day])
+		if err != nil {
+			return fmt.Errorf("invalid range time [%s] for [%s]: %s", r[day], day, err)
+		}
+	}
+	return nil
+}
+
+// validateRangeDay checks day specification: weekday, month, "day" or date, optionally with numbers, "-" span and "/ N" stride
+func validateRangeDay(day string) error {
+	tokens := strings.Fields(day)
+	if len(tokens) == 0 {
+		return fmt.Errorf("empty")
+	}
+	hasDay := false
+	for i := 0; i < len(tokens); i++ {
+		t := strings.ToLower(tokens[i])
+		switch {
+		case weekdays[t] || months[t] || t == "day":
+			hasDay = true
+		case rangeDateRe.MatchString(t):
+			if _, err := time.Parse("2006-01-02", t); err != nil {
+				return fmt.Errorf("invalid date %s", t)
+			}
+			hasDay = true
+		case rangeNumberRe.MatchString(t):
+		case t == "-":
+			if i == 0 || i == len(tokens)-1 {
+				return fmt.Errorf("unfinished span")
+			}
+		case t == "/":
+			if i != len(tokens)-2 || !rangeNumberRe.MatchString(tokens[i+1]) || strings.HasPrefix(tokens[i+1], "-") {
+				return fmt.Errorf("stride has to be a positive number at the end")
+			}
+			i++
+		default:
+			return fmt.Errorf("unknown token %s", tokens[i])
+		}
+	}
+	if !hasDay {
+		return fmt.Errorf("no weekday, month, date or \"day\" keyword")
+	}
+	return nil
+}
+
+// validateRangeTimes checks comma separated HH:MM-HH:MM ranges, 24:00 is allowed as the end of day
+func validateRangeTimes(times string) error {
+	for _, t := range strings.Split(times, ",") {
+		m := rangeTimeRe.FindStringSubmatch(strings.TrimSpace(t))
+		if m == nil {
+			return fmt.Errorf("%s is not in HH:MM-HH:MM format", strings.TrimSpace(t))
+		}
+		start, err := rangeMinutes(m[1], m[2])
+		if err != nil {
+			return err
+		}
+		end, err := rangeMinutes(m[3], m[4])
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return fmt.Errorf("%s ends before it starts", strings.TrimSpace(t))
+		}
+	}
+	return nil
+}
+
+func rangeMinutes(hours string, minutes string) (int, error) {
+	h, _ := strconv.Atoi(hours)
+	m, _ := strconv.Atoi(minutes)
+	if h > 24 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %s:%s", hours, minutes)
+	}
+	return h*60 + m, nil
+}
+
+// ScheduledDowntime is recurring downtime config object
+type ScheduledDowntime struct {
+	// short name, full object name is "host!name" or "host!service!name"
+	Name        string
+	HostName    string
+	ServiceName string
+	Author      string
+	Comment     string
+	// fixed downtime lasts for the whole range, flexible one for Duration after problem starts within the range
+	Fixed        bool
+	Duration     time.Duration
+	Ranges       DowntimeRanges
+	ChildOptions ChildOptions
+	// optional, zone object is synced to
+	Zone string
+}
+
+type scheduledDowntimeAttrs struct {
+	Name         string         `json:"name,omitempty"`
+	HostName     string         `json:"host_name,omitempty"`
+	ServiceName  string         `json:"service_name,omitempty"`
+	Author       string         `json:"author"`
+	Comment      string         `json:"comment"`
+	Fixed        bool           `json:"fixed"`
+	Duration     float64        `json:"duration"`
+	Ranges       DowntimeRanges `json:"ranges"`
+	ChildOptions ChildOptions   `json:"child_options,omitempty"`
+	Zone         string         `json:"zone,omitempty"`
+}
+
+// FullName returns Icinga2 object name
+func (d *ScheduledDowntime) FullName() string {
+	if d.ServiceName == "" {
+		return d.HostName + "!" + d.Name
+	}
+	return d.HostName + "!" + d.ServiceName + "!" + d.Name
+}
+
+// Validate checks downtime before upload
+func (d *ScheduledDowntime) Validate() error {
+	if d.Name == "" || d.HostName == "" {
+		return fmt.Errorf("scheduled downtime needs name and host name")
+	}
+	if strings.Contains(d.Name, "!") {
+		return fmt.Errorf("scheduled downtime name can't contain \"!\"")
+	}
+	if d.Author == "" || d.Comment == "" {
+		return fmt.Errorf("scheduled downtime needs author and comment")
+	}
+	if !d.Fixed && d.Duration <= 0 {
+		return fmt.Errorf("flexible downtime needs duration set")
+	}
+	switch d.ChildOptions {
+	case "", DowntimeNoChildren, DowntimeTriggeredChildren, DowntimeNonTriggeredChildren:
+	default:
+		return fmt.Errorf("invalid child options %s", d.ChildOptions)
+	}
+	return d.Ranges.Validate()
+}
+
+func (d *ScheduledDowntime) attrs() scheduledDowntimeAttrs {
+	return scheduledDowntimeAttrs{
+		HostName:     d.HostName,
+		ServiceName:  d.ServiceName,
+		Author:       d.Author,
+		Comment:      d.Comment,
+		Fixed:        d.Fixed,
+		Duration:     d.Duration.Seconds(),
+		Ranges:       d.Ranges,
+		ChildOptions: d.ChildOptions,
+		Zone:         d.Zone,
+	}
+}
+
+func (i *Icinga2APIResponse) GetScheduledDowntimes() (v []ScheduledDowntime) {
+	for _, obj := range i.Results {
+		if obj.Type != "ScheduledDowntime" {
+			continue
+		}
+		var attrs scheduledDowntimeAttrs
+		err := json.Unmarshal(obj.Attrs, &attrs)
+		if err != nil {
+			log.Printf("error unmarshalling scheduled downtime %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			continue
+		}
+		v = append(v, ScheduledDowntime{
+			Name:         attrs.Name,
+			HostName:     attrs.HostName,
+			ServiceName:  attrs.ServiceName,
+			Author:       attrs.Author,
+			Comment:      attrs.Comment,
+			Fixed:        attrs.Fixed,
+			Duration:     time.Duration(attrs.Duration * float64(time.Second)),
+			Ranges:       attrs.Ranges,
+			ChildOptions: attrs.ChildOptions,
+			Zone:         attrs.Zone,
+		})
+	}
+	return v
+}
+
+// GetScheduledDowntimes returns scheduled downtimes matching filter, all if filter is empty
+func (a *API) GetScheduledDowntimes(filter string) (m []ScheduledDowntime, err error) {
+	i, err := a.getObjects("ScheduledDowntimes", QueryOptions{Filter: filter})
+	if err != nil {
+		return m, err
+	}
+	return i.GetScheduledDowntimes(), nil
+}
+
+// CreateScheduledDowntime validates and creates scheduled downtime object
+func (a *API) CreateScheduledDowntime(d ScheduledDowntime) error {
+	err := d.Validate()
+	if err != nil {
+		return err
+	}
+	body, err := a.request("PUT", scheduledDowntimePath(d.FullName()), map[string]interface{}{"attrs": d.attrs()})
+	if err != nil {
+		return err
+	}
+	return statusError(body, "error creating scheduled downtime "+d.FullName())
+}
+
+// UpdateScheduledDowntime validates and replaces attributes of existing scheduled downtime. Host and service can't be changed
+func (a *API) UpdateScheduledDowntime(d ScheduledDowntime) error {
+	err := d.Validate()
+	if err != nil {
+		return err
+	}
+	attrs := d.attrs()
+	attrs.HostName = ""
+	attrs.ServiceName = ""
+	attrs.Zone = ""
+	body, err := a.request("POST", scheduledDowntimePath(d.FullName()), map[string]interface{}{"attrs": attrs})
+	if err != nil {
+		return err
+	}
+	return statusError(body, "error updating scheduled downtime "+d.FullName())
+}
+
+// DeleteScheduledDowntime deletes scheduled downtime by full name together with downtimes it created
+func (a *API) DeleteScheduledDowntime(fullName string) error {
+	body, err := a.request("DELETE", scheduledDowntimePath(fullName)+"?cascade=1", nil)
+	if err != nil {
+		return err
+	}
+	return statusError(body, "error deleting scheduled downtime "+fullName)
+}
+
+func scheduledDowntimePath(fullName string) string {
+	return "/v1/objects/scheduleddowntimes/" + url.PathEscape(fullName)
+}
@@ -0,0 +1,130 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func testScheduledDowntime() ScheduledDowntime {
+	return ScheduledDowntime{
+		Name:         "patch-window",
+		HostName:     "t1-host1",
+		Author:       "ops",
+		Comment:      "weekly patching",
+		Fixed:        true,
+		Ranges:       DowntimeRanges{"sunday": "02:00-04:00"},
+		ChildOptions: DowntimeNoChildren,
+	}
+}
+
+func TestDowntimeRanges_Validate(t *testing.T) {
+	valid := []string{"sunday", "Monday", "monday 1", "monday -1", "day 1", "day -1", "january 1", "monday 2 january",
+		"2021-07-04", "2021-07-04 - 2021-07-07", "monday - friday", "day 1 - 15", "day 1 - 15 / 5", "2021-07-04 / 7"}
+	for _, day := range valid {
+		assert.Nil(t, DowntimeRanges{day: "02:00-04:00"}.Validate(), day)
+	}
+	invalid := []string{"", "sundy", "1", "monday -", "day 1 / x", "day / 2 1", "2021-13-01", "monday / -1"}
+	for _, day := range invalid {
+		assert.NotNil(t, DowntimeRanges{day: "02:00-04:00"}.Validate(), day)
+	}
+	assert.Nil(t, DowntimeRanges{"sunday": "00:00-01:00, 22:00-24:00"}.Validate())
+	for _, times := range []string{"", "2:00", "02:00-01:00", "02:00-02:00", "25:00-26:00", "23:00-24:30", "02:60-03:00", "2-4"} {
+		assert.NotNil(t, DowntimeRanges{"sunday": times}.Validate(), times)
+	}
+	assert.EqualError(t, DowntimeRanges{}.Validate(), "no ranges")
+	assert.EqualError(t, DowntimeRanges{"sunday": "04:00-02:00"}.Validate(), "invalid range time [04:00-02:00] for [sunday]: 04:00-02:00 ends before it starts")
+}
+
+func TestScheduledDowntime_Validate(t *testing.T) {
+	d := testScheduledDowntime()
+	assert.Nil(t, d.Validate())
+	d.Fixed = false
+	assert.EqualError(t, d.Validate(), "flexible downtime needs duration set")
+	d.Duration = time.Hour
+	assert.Nil(t, d.Validate())
+	d.ChildOptions = "all"
+	assert.NotNil(t, d.Validate())
+	d = testScheduledDowntime()
+	d.Name = "a!b"
+	assert.NotNil(t, d.Validate())
+	d = testScheduledDowntime()
+	d.Author = ""
+	assert.NotNil(t, d.Validate())
+}
+
+func TestAPI_GetScheduledDowntimes(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/ScheduledDowntimes", "v1.objects.scheduleddowntimes.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	d, err := Api.GetScheduledDowntimes("")
+	require.Nil(t, err)
+	require.Len(t, d, 2)
+	assert.Equal(t, "t1-host1!patch-window", d[0].FullName())
+	assert.True(t, d[0].Fixed)
+	assert.Equal(t, DowntimeRanges{"sunday": "02:00-04:00"}, d[0].Ranges)
+	assert.Equal(t, "t1-host1!POSTGRES!backup", d[1].FullName())
+	assert.Equal(t, 30*time.Minute, d[1].Duration)
+	assert.Equal(t, DowntimeNoChildren, d[1].ChildOptions)
+}
+
+func TestAPI_CreateScheduledDowntime(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/scheduleddowntimes/t1-host1!patch-window": "v1.objects.create.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	require.Nil(t, Api.CreateScheduledDowntime(testScheduledDowntime()))
+	require.Len(t, ts.Requests(), 1)
+	assert.Equal(t, "PUT", ts.Requests()[0].Method)
+	assert.Equal(t, "/v1/objects/scheduleddowntimes/t1-host1%21patch-window", ts.Requests()[0].URI)
+	assert.JSONEq(t, `{"attrs":{"host_name":"t1-host1","author":"ops","comment":"weekly patching","fixed":true,"duration":0,
+		"ranges":{"sunday":"02:00-04:00"},"child_options":"DowntimeNoChildren"}}`, ts.Requests()[0].Body)
+
+	invalid := testScheduledDowntime()
+	invalid.Ranges = DowntimeRanges{"sunday": "4-2"}
+	assert.NotNil(t, Api.CreateScheduledDowntime(invalid))
+	assert.Len(t, ts.Requests(), 1, "invalid downtime should not be sent")
+}
+
+func TestAPI_CreateScheduledDowntime_Error(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/scheduleddowntimes/t1-host1!patch-window": "v1.objects.create.error.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	err = Api.CreateScheduledDowntime(testScheduledDowntime())
+	assert.EqualError(t, err, "error creating scheduled downtime t1-host1!patch-window: [500] Object could not be created.: Error: Object 't1-host1!patch-window' of type 'ScheduledDowntime' re-defined.")
+}
+
+func TestAPI_UpdateScheduledDowntime(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/scheduleddowntimes/t1-host1!patch-window": "v1.objects.modify.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	d := testScheduledDowntime()
+	d.Ranges["saturday"] = "02:00-04:00"
+	require.Nil(t, Api.UpdateScheduledDowntime(d))
+	require.Len(t, ts.Requests(), 1)
+	assert.Equal(t, "POST", ts.Requests()[0].Method)
+	assert.JSONEq(t, `{"attrs":{"author":"ops","comment":"weekly patching","fixed":true,"duration":0,
+		"ranges":{"saturday":"02:00-04:00","sunday":"02:00-04:00"},"child_options":"DowntimeNoChildren"}}`, ts.Requests()[0].Body)
+}
+
+func TestAPI_DeleteScheduledDowntime(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/scheduleddowntimes/t1-host1!patch-window": "v1.objects.delete.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	require.Nil(t, Api.DeleteScheduledDowntime("t1-host1!patch-window"))
+	require.Len(t, ts.Requests(), 1)
+	assert.Equal(t, "DELETE", ts.Requests()[0].Method)
+	assert.Equal(t, "/v1/objects/scheduleddowntimes/t1-host1%21patch-window?cascade=1", ts.Requests()[0].URI)
+	assert.Empty(t, ts.Requests()[0].Body)
+}
@@ -0,0 +1,109 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-monitoring"
+)
+
+// Icinga2 host states as returned by API. Unreachable is not a state in Icinga2, it is computed from last_reachable
+const (
+	IcingaHostUp   = 0
+	IcingaHostDown = 1
+)
+
+// Icinga2 service states as returned by API
+const (
+	IcingaServiceOK       = 0
+	IcingaServiceWarning  = 1
+	IcingaServiceCritical = 2
+	IcingaServiceUnknown  = 3
+)
+
+// State is an Icinga2 object state mapped into monitoring one
+type State struct {
+	// monitoring.Host* constant for hosts, monitoring.Status* for services
+	State uint8
+	// hard state, soft one means check will be retried before notifying
+	Hard bool
+	// false if one of the object's parents is in problem state
+	Reachable bool
+	// problem is acknowledged or in downtime, always false for UP/OK objects
+	Handled bool
+}
+
+// HostStateMapper maps Icinga2 host into monitoring state
+type HostStateMapper func(h *Host) State
+
+// ServiceStateMapper maps Icinga2 service into monitoring state
+type ServiceStateMapper func(s *Service) State
+
+// commonState maps state attributes shared by hosts and services, only problem can be handled
+func commonState(c *Checkable, problem bool) State {
+	return State{
+		Hard:      c.StateType == 1,
+		Reachable: c.LastReachable,
+		Handled:   problem && (c.Handled || c.Acknowledgement > 0 || c.DowntimeDepth > 0),
+	}
+}
+
+// DefaultHostState maps UP/DOWN host states, reporting down host as unreachable if its parent is down
+func DefaultHostState(h *Host) State {
+	s := commonState(&h.Checkable, int(h.State) != IcingaHostUp)
+	switch int(h.State) {
+	case IcingaHostUp:
+		s.State = monitoring.HostUp
+	case IcingaHostDown:
+		if h.LastReachable {
+			s.State = monitoring.HostDown
+		} else {
+			s.State = monitoring.HostUnreachable
+		}
+	// not returned by API but used in IDO/legacy interfaces
+	case 2:
+		s.State = monitoring.HostUnreachable
+	default:
+		s.State = monitoring.HostInvalid
+	}
+	return s
+}
+
+// DefaultServiceState maps OK/WARNING/CRITICAL/UNKNOWN service states
+func DefaultServiceState(svc *Service) State {
+	s := commonState(&svc.Checkable, int(svc.State) != IcingaServiceOK)
+	switch int(svc.State) {
+	case IcingaServiceOK:
+		s.State = monitoring.StatusOk
+	case IcingaServiceWarning:
+		s.State = monitoring.StatusWarning
+	case IcingaServiceCritical:
+		s.State = monitoring.StatusCritical
+	case IcingaServiceUnknown:
+		s.State = monitoring.StatusUnknown
+	default:
+		s.State = monitoring.StatusInvalid
+	}
+	return s
+}
+
+// SetHostStateMapper sets function used to map hosts into monitoring states, nil restores the default
+func (a *API) SetHostStateMapper(f HostStateMapper) {
+	a.hostStateMapper = f
+}
+
+// SetServiceStateMapper sets function used to map services into monitoring states, nil restores the default
+func (a *API) SetServiceStateMapper(f ServiceStateMapper) {
+	a.serviceStateMapper = f
+}
+
+func (a *API) hostState() HostStateMapper {
+	if a.hostStateMapper == nil {
+		return DefaultHostState
+	}
+	return a.hostStateMapper
+}
+
+func (a *API) serviceState() ServiceStateMapper {
+	if a.serviceStateMapper == nil {
+		return DefaultServiceState
+	}
+	return a.serviceStateMapper
+}
@@ -0,0 +1,94 @@
+package icinga2
+
+import (
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDefaultHostState(t *testing.T) {
+	tests := []struct {
+		name  string
+		host  Checkable
+		state State
+	}{
+		{"up", Checkable{State: 0, StateType: 1, LastReachable: true}, State{State: monitoring.HostUp, Hard: true, Reachable: true}},
+		{"down soft", Checkable{State: 1, StateType: 0, LastReachable: true}, State{State: monitoring.HostDown, Reachable: true}},
+		{"unreachable", Checkable{State: 1, StateType: 1}, State{State: monitoring.HostUnreachable, Hard: true}},
+		{"down acked", Checkable{State: 1, StateType: 1, LastReachable: true, Acknowledgement: 1}, State{State: monitoring.HostDown, Hard: true, Reachable: true, Handled: true}},
+		{"down handled", Checkable{State: 1, StateType: 1, LastReachable: true, Handled: true}, State{State: monitoring.HostDown, Hard: true, Reachable: true, Handled: true}},
+		{"up in downtime", Checkable{State: 0, StateType: 1, LastReachable: true, DowntimeDepth: 1}, State{State: monitoring.HostUp, Hard: true, Reachable: true}},
+		{"invalid", Checkable{State: 7, LastReachable: true}, State{State: monitoring.HostInvalid, Reachable: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.state, DefaultHostState(&Host{Checkable: tt.host}))
+		})
+	}
+}
+
+func TestDefaultServiceState(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Checkable
+		state   State
+	}{
+		{"ok", Checkable{State: 0, StateType: 1, LastReachable: true}, State{State: monitoring.StatusOk, Hard: true, Reachable: true}},
+		{"warning", Checkable{State: 1, StateType: 0, LastReachable: true}, State{State: monitoring.StatusWarning, Reachable: true}},
+		{"critical in downtime", Checkable{State: 2, StateType: 1, LastReachable: true, DowntimeDepth: 1}, State{State: monitoring.StatusCritical, Hard: true, Reachable: true, Handled: true}},
+		{"ok in downtime", Checkable{State: 0, StateType: 1, LastReachable: true, DowntimeDepth: 1, Acknowledgement: 1}, State{State: monitoring.StatusOk, Hard: true, Reachable: true}},
+		{"unknown unreachable", Checkable{State: 3, StateType: 1}, State{State: monitoring.StatusUnknown, Hard: true}},
+		{"invalid", Checkable{State: 4}, State{State: monitoring.StatusInvalid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.state, DefaultServiceState(&Service{Checkable: tt.service}))
+		})
+	}
+}
+
+func TestAPI_SetStateMapper(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{
+		"/v1/objects/Hosts":    "v1.objects.hosts.json",
+		"/v1/objects/Services": "v1.objects.services.json",
+	})
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	Api.SetHostStateMapper(func(h *Host) State {
+		return State{State: monitoring.HostDown, Hard: false}
+	})
+	Api.SetServiceStateMapper(func(s *Service) State {
+		return State{State: monitoring.StatusUnknown, Hard: true}
+	})
+	hosts, err := Api.GetHosts()
+	require.Nil(t, err)
+	for _, h := range hosts {
+		assert.Equal(t, monitoring.HostDown, int(h.State))
+		assert.False(t, h.StateHard)
+	}
+	services, err := Api.GetServices()
+	require.Nil(t, err)
+	for _, s := range services {
+		assert.Equal(t, monitoring.StatusUnknown, int(s.State))
+	}
+	Api.SetHostStateMapper(nil)
+	hosts, err = Api.GetHosts()
+	require.Nil(t, err)
+	assert.Equal(t, monitoring.HostUp, int(hosts[0].State))
+}
+
+func TestProxy_SetStateMappers(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	p.SetStateMappers(func(h *Host) State { return State{State: monitoring.HostUnreachable} }, nil)
+	hosts, err := p.GetHosts()
+	require.Nil(t, err)
+	assert.Equal(t, monitoring.HostUnreachable, int(hosts[0].State))
+}
@@ -0,0 +1,78 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ApplicationStatus is status of the Icinga2 instance API is connected to
+type ApplicationStatus struct {
+	// set by Proxy
+	Server              string    `json:"server,omitempty"`
+	NodeName            string    `json:"node_name"`
+	Version             string    `json:"version"`
+	Environment         string    `json:"environment"`
+	PID                 float64   `json:"pid"`
+	ProgramStart        Timestamp `json:"program_start"`
+	EnableNotifications bool      `json:"enable_notifications"`
+	EnableHostChecks    bool      `json:"enable_host_checks"`
+	EnableServiceChecks bool      `json:"enable_service_checks"`
+	EnableEventHandlers bool      `json:"enable_event_handlers"`
+	EnableFlapping      bool      `json:"enable_flapping"`
+	EnablePerfdata      bool      `json:"enable_perfdata"`
+}
+
+type applicationStatusResponse struct {
+	Results []struct {
+		Name   string `json:"name"`
+		Status struct {
+			IcingaApplication struct {
+				App ApplicationStatus `json:"app"`
+			} `json:"icingaapplication"`
+		} `json:"status"`
+	} `json:"results"`
+	Error  float64 `json:"error"`
+	Status string  `json:"status"`
+}
+
+// GetApplicationStatus returns version and global feature flags of Icinga2 instance
+func (a *API) GetApplicationStatus() (s ApplicationStatus, err error) {
+	body, err := a.request("GET", "/v1/status/IcingaApplication", nil)
+	if err != nil {
+		return s, err
+	}
+	var resp applicationStatusResponse
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return s, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+	}
+	if resp.Error != 0 {
+		return s, fmt.Errorf("error getting status: [%d] %s", int(resp.Error), resp.Status)
+	}
+	if len(resp.Results) == 0 {
+		return s, fmt.Errorf("error getting status: empty response")
+	}
+	return resp.Results[0].Status.IcingaApplication.App, nil
+}
+
+// GetApplicationStatus returns status of each server that responded, ordered by server name.
+// It fails only if no server responded, use Servers to check them one by one
+func (a *Proxy) GetApplicationStatus() (m []ApplicationStatus, err error) {
+	var lock sync.Mutex
+	m = make([]ApplicationStatus, 0)
+	errs := a.each(func(name string, s *API) error {
+		status, err := s.GetApplicationStatus()
+		if err != nil {
+			return err
+		}
+		status.Server = name
+		lock.Lock()
+		defer lock.Unlock()
+		m = append(m, status)
+		return nil
+	})
+	sort.Slice(m, func(i, j int) bool { return m[i].Server < m[j].Server })
+	return m, proxyError(errs)
+}
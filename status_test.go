@@ -0,0 +1,44 @@
+package icinga2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_GetApplicationStatus(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/status/IcingaApplication", "v1.status.IcingaApplication.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	status, err := Api.GetApplicationStatus()
+	require.Nil(t, err)
+	assert.Equal(t, "t1-mon1", status.NodeName)
+	assert.Equal(t, "r2.13.1-1", status.Version)
+	assert.Equal(t, int64(1625400000), status.ProgramStart.Unix())
+	assert.False(t, status.EnableNotifications)
+	assert.True(t, status.EnableHostChecks)
+}
+
+func TestProxy_GetApplicationStatus(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/status/IcingaApplication", "v1.status.IcingaApplication.json")
+	defer ts.Close()
+	Api, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: "http://127.0.0.1:1", User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	status, err := Api.GetApplicationStatus()
+	require.Nil(t, err, "one server is enough")
+	require.Len(t, status, 1)
+	assert.Equal(t, "s1", status[0].Server)
+
+	Api, err = NewProxy(map[string]Icinga2ServerConfig{
+		"s2": {ServerURL: "http://127.0.0.1:1", User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	_, err = Api.GetApplicationStatus()
+	assert.Error(t, err)
+}
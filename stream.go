@@ -0,0 +1,144 @@
+package icinga2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeObjects walks "results" array of the response token by token and calls fn for each object,
+// so only one object at a time is kept in memory. Error returned by fn stops decoding
+func decodeObjects(r io.Reader, fn func(obj *Icinga2APIObject) error) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	var status Icinga2StatusPart
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("error decoding json: %s", err)
+		}
+		switch t {
+		case "results":
+			if err := expectDelim(dec, '['); err != nil {
+				return err
+			}
+			for dec.More() {
+				var obj Icinga2APIObject
+				if err := dec.Decode(&obj); err != nil {
+					return fmt.Errorf("error decoding json: %s", err)
+				}
+				if err := fn(&obj); err != nil {
+					return err
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		case "error":
+			if err := dec.Decode(&status.Error); err != nil {
+				return fmt.Errorf("error decoding json: %s", err)
+			}
+		case "status":
+			if err := dec.Decode(&status.Status); err != nil {
+				return fmt.Errorf("error decoding json: %s", err)
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("error decoding json: %s", err)
+			}
+		}
+	}
+	if status.Error != 0 {
+		return &APIError{Code: int(status.Error), Status: status.Status}
+	}
+	return expectDelim(dec, '}')
+}
+
+// collectObjects decodes all objects from body. Decoding errors include beginning of the body to show what server sent
+func collectObjects(body io.Reader) ([]Icinga2APIObject, error) {
+	head := &bodyHead{}
+	var results []Icinga2APIObject
+	err := decodeObjects(io.TeeReader(body, head), func(obj *Icinga2APIObject) error {
+		results = append(results, *obj)
+		return nil
+	})
+	if _, ok := err.(*APIError); err != nil && !ok {
+		return results, fmt.Errorf("%s | %s", err, head)
+	}
+	return results, err
+}
+
+// bodyHead keeps first bodyHeadSize bytes written to it
+type bodyHead struct {
+	bytes.Buffer
+}
+
+const bodyHeadSize = 512
+
+func (h *bodyHead) Write(p []byte) (int, error) {
+	if n := bodyHeadSize - h.Len(); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		h.Buffer.Write(p[:n])
+	}
+	return len(p), nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("error decoding json: %s", err)
+	}
+	if t != delim {
+		return fmt.Errorf("error decoding json: expected %s, got %v", delim, t)
+	}
+	return nil
+}
+
+// WalkObjects streams objects of objType ("Hosts", "Services"...) matching filter, calling fn for each of them.
+// Unlike Get* methods it does not keep whole response in memory. Error returned by fn stops the walk and is returned
+func (a *API) WalkObjects(objType string, filter string, fn func(obj *Icinga2APIObject) error) error {
+	resp, err := a.queryObjects(objType, QueryOptions{Filter: filter})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeObjects(resp.Body, fn)
+}
+
+// WalkHosts streams hosts matching filter, calling fn for each of them
+func (a *API) WalkHosts(filter string, fn func(h *Host) error) error {
+	return a.WalkObjects("Hosts", filter, func(obj *Icinga2APIObject) error {
+		if obj.Type != "Host" {
+			return nil
+		}
+		var host Host
+		host.LastReachable = true
+		if err := json.Unmarshal(obj.Attrs, &host); err != nil {
+			log.Printf("error unmarshalling host %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			return nil
+		}
+		return fn(&host)
+	})
+}
+
+// WalkServices streams services matching filter, calling fn for each of them
+func (a *API) WalkServices(filter string, fn func(s *Service) error) error {
+	return a.WalkObjects("Services", filter, func(obj *Icinga2APIObject) error {
+		if obj.Type != "Service" {
+			return nil
+		}
+		var service Service
+		service.LastReachable = true
+		if err := json.Unmarshal(obj.Attrs, &service); err != nil {
+			log.Printf("error unmarshalling service %s: %s | %s", obj.Name, err, string(obj.Attrs))
+			return nil
+		}
+		return fn(&service)
+	})
+}
@@ -0,0 +1,151 @@
+package icinga2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeObjects(t *testing.T) {
+	f, err := ioutil.ReadFile("testdata/v1.objects.services.json")
+	require.Nil(t, err)
+	var names []string
+	err = decodeObjects(bytes.NewReader(f), func(obj *Icinga2APIObject) error {
+		names = append(names, obj.Name)
+		return nil
+	})
+	require.Nil(t, err)
+	assert.Len(t, names, 7)
+	assert.Equal(t, "t1-host1!POSTGRES_REPLICATION_REMOTE", names[0])
+}
+
+func TestDecodeObjects_Errors(t *testing.T) {
+	f, err := ioutil.ReadFile("testdata/error.no-objects-found.json")
+	require.Nil(t, err)
+	err = decodeObjects(bytes.NewReader(f), func(obj *Icinga2APIObject) error { return nil })
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "No objects found")
+
+	err = decodeObjects(strings.NewReader(`{"results":[{"name":"a"},`), func(obj *Icinga2APIObject) error { return nil })
+	assert.Error(t, err, "truncated")
+
+	err = decodeObjects(strings.NewReader(`[]`), func(obj *Icinga2APIObject) error { return nil })
+	assert.Error(t, err, "not an object")
+
+	stop := errors.New("stop")
+	count := 0
+	err = decodeObjects(strings.NewReader(`{"results":[{"name":"a"},{"name":"b"}]}`), func(obj *Icinga2APIObject) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestCollectObjects(t *testing.T) {
+	results, err := collectObjects(strings.NewReader(`{"results":[{"name":"a"},{"name":"b"}]}`))
+	require.Nil(t, err)
+	assert.Len(t, results, 2)
+
+	_, err = collectObjects(strings.NewReader(`<html>Bad Gateway</html>`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "<html>Bad Gateway</html>", "body in decoding error")
+
+	_, err = collectObjects(strings.NewReader(`{"error":404,"status":"No objects found."}`))
+	assert.Equal(t, &APIError{Code: 404, Status: "No objects found."}, err)
+}
+
+func TestAPI_WalkHosts(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts", "v1.objects.hosts.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	hosts := make(map[string]bool)
+	err = Api.WalkHosts("", func(h *Host) error {
+		hosts[h.Name] = true
+		return nil
+	})
+	require.Nil(t, err)
+	assert.Len(t, hosts, 7)
+	assert.True(t, hosts["t1-host1"])
+}
+
+func TestAPI_WalkServices(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Services", "v1.objects.services.json")
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	count := 0
+	err = Api.WalkServices("", func(s *Service) error {
+		count++
+		assert.NotEmpty(t, s.HostName)
+		return nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 7, count)
+}
+
+// largeServicesResponse builds response with n services by repeating the fixture
+func largeServicesResponse(b *testing.B, n int) []byte {
+	f, err := ioutil.ReadFile("testdata/v1.objects.services.json")
+	require.Nil(b, err)
+	var fixture struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	require.Nil(b, json.Unmarshal(f, &fixture))
+	out := struct {
+		Results []json.RawMessage `json:"results"`
+	}{Results: make([]json.RawMessage, 0, n)}
+	for len(out.Results) < n {
+		out.Results = append(out.Results, fixture.Results[len(out.Results)%len(fixture.Results)])
+	}
+	data, err := json.Marshal(out)
+	require.Nil(b, err)
+	return data
+}
+
+func benchmarkServer(b *testing.B, n int) *httptest.Server {
+	data := largeServicesResponse(b, n)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+}
+
+func BenchmarkGetServicesDetailed_10k(b *testing.B) {
+	ts := benchmarkServer(b, 10000)
+	defer ts.Close()
+	Api, _ := New(ts.URL, TestUser, TestPass)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		services, err := Api.GetServicesDetailed("")
+		if err != nil || len(services) != 10000 {
+			b.Fatalf("got %d services: %s", len(services), err)
+		}
+	}
+}
+
+func BenchmarkWalkServices_10k(b *testing.B) {
+	ts := benchmarkServer(b, 10000)
+	defer ts.Close()
+	Api, _ := New(ts.URL, TestUser, TestPass)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		count := 0
+		err := Api.WalkServices("", func(s *Service) error {
+			count++
+			return nil
+		})
+		if err != nil || count != 10000 {
+			b.Fatalf("got %d services: %s", count, err)
+		}
+	}
+}
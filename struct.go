@@ -20,14 +20,22 @@ type Icinga2StatusPart struct {
 	Error  float64 `json:"error"` // yes, api returns floats as error code
 	Status string  `json:"status"`
 	Name   string  `json:"name"`
+	// id used by legacy interfaces, set for created downtimes and comments
+	LegacyID float64 `json:"legacy_id,omitempty"`
+	// detailed errors of object creation/modification
+	Errors []string `json:"errors,omitempty"`
 }
 
 type Icinga2APIObject struct {
 	Attrs json.RawMessage
 	Type  string
 	Name  string
+	Joins map[string]json.RawMessage
 }
 
+// Icinga2APIHost is a minimal host decoding.
+//
+// Deprecated: use Host
 type Icinga2APIHost struct {
 	Name        string  `json:"name"`
 	DisplayName string  `json:"display_name"`
@@ -49,6 +57,9 @@ type Icinga2APIHost struct {
 	CheckResult           Icinga2APICheckResult `json:"last_check_result"`
 }
 
+// Icinga2APIService is a minimal service decoding.
+//
+// Deprecated: use Service
 type Icinga2APIService struct {
 	Host        string  `json:"host_name"`
 	Service     string  `json:"name"`
@@ -71,92 +82,36 @@ type Icinga2APIService struct {
 	CheckResult           Icinga2APICheckResult `json:"last_check_result"`
 }
 
-type Icinga2APICheckResult struct {
-	CheckFrom string `json:"check_source"`
-	Message   string `json:"output"`
-}
+// Icinga2APICheckResult is kept for compatibility, see CheckResult
+type Icinga2APICheckResult = CheckResult
 
 func (i *Icinga2APIResponse) GetHosts() (v []monitoring.Host) {
-	for _, obj := range i.Results {
-		if obj.Type != "Host" {
-			continue
-		}
-		var apiHost Icinga2APIHost
-		err := json.Unmarshal(obj.Attrs, &apiHost)
-		if err != nil {
-			log.Printf("error unmarshalling host %s: %s | %s", obj.Name, err, string(obj.Attrs))
-			continue
-		}
+	return i.GetHostsWith(DefaultHostState)
+}
 
-		host := monitoring.Host{Host: apiHost.Name}
-		host.State = uint8(apiHost.State) + 1
-		host.Timestamp = unixTsToTs(apiHost.LastCheck)
-		host.LastStateChange = unixTsToTs(apiHost.LastStateChange)
-		host.LastHardStateChange = unixTsToTs(apiHost.LastHardStateChange)
-		host.Flapping = apiHost.Flapping
-		host.CheckMessage = apiHost.CheckResult.Message
-		host.DisplayName = apiHost.DisplayName
-		if apiHost.StateType == 1.0 {
-			host.StateHard = true
-		}
-		if apiHost.DowntimeDepth > 0 {
-			host.Downtime = true
-		}
-		if apiHost.Acknowledgement > 0 {
-			host.Acknowledged = true
-		}
-		if len(apiHost.ActionURL) > 0 {
-			host.URL = apiHost.ActionURL
-		} else if len(apiHost.NotesURL) > 0 {
-			host.URL = apiHost.NotesURL
-		}
-		v = append(v, host)
+// GetHostsWith returns hosts with state mapped by f
+func (i *Icinga2APIResponse) GetHostsWith(f HostStateMapper) (v []monitoring.Host) {
+	for _, host := range i.GetHostsDetailed() {
+		v = append(v, host.ToMonitoringWith(f))
 	}
 	return v
 }
 
 func (i *Icinga2APIResponse) GetServices() (v []monitoring.Service) {
-	for _, obj := range i.Results {
-		if obj.Type != "Service" {
-			continue
-		}
-		var apiService Icinga2APIService
-		err := json.Unmarshal(obj.Attrs, &apiService)
-		if err != nil {
-			log.Printf("error unmarshalling host %s: %s | %s", obj.Name, err, string(obj.Attrs))
-			continue
-		}
-
-		service := monitoring.Service{
-			Host:    apiService.Host,
-			Service: apiService.Service,
-		}
-		service.State = uint8(apiService.State) + 1
-		service.Timestamp = unixTsToTs(apiService.LastCheck)
-		service.LastStateChange = unixTsToTs(apiService.LastStateChange)
-		service.Flapping = apiService.Flapping
-		service.CheckMessage = apiService.CheckResult.Message
-		service.DisplayName = apiService.DisplayName
-		if apiService.StateType == 1.0 {
-			service.StateHard = true
-		}
-		if apiService.DowntimeDepth > 0 {
-			service.Downtime = true
-		}
-		if apiService.Acknowledgement > 0 {
-			service.Acknowledged = true
-		}
-		if len(apiService.ActionURL) > 0 {
-			service.URL = apiService.ActionURL
-		} else if len(apiService.NotesURL) > 0 {
-			service.URL = apiService.NotesURL
-		}
+	return i.GetServicesWith(DefaultServiceState)
+}
 
-		v = append(v, service)
+// GetServicesWith returns services with state mapped by f
+func (i *Icinga2APIResponse) GetServicesWith(f ServiceStateMapper) (v []monitoring.Service) {
+	for _, service := range i.GetServicesDetailed() {
+		v = append(v, service.ToMonitoringWith(f))
 	}
 	return v
 }
 
+// GetDowntimeList returns hosts downtime was scheduled for.
+//
+// Deprecated: use GetActionResults, which also reports failures
 func (i *Icinga2StatusResponseOk) GetDowntimeList() []string {
 	objects := make([]string, 0)
 	for _, obj := range i.Results {
@@ -0,0 +1,78 @@
+package topology
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WriteDOT writes graph in Graphviz DOT format, edges point from child to parent and problem nodes are red
+func (g *Graph) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph topology {")
+	fmt.Fprintln(b, "\trankdir=BT;")
+	for _, id := range g.sortedIDs() {
+		n := g.Nodes[id]
+		attrs := "shape=box"
+		if n.Service != "" {
+			attrs = "shape=ellipse"
+		}
+		if n.Problem {
+			attrs += ",color=red"
+		}
+		if !n.Known {
+			attrs += ",style=dashed"
+		}
+		fmt.Fprintf(b, "\t%s [%s];\n", strconv.Quote(id), attrs)
+	}
+	for _, e := range g.sortedEdges() {
+		fmt.Fprintf(b, "\t%s -> %s [label=%s];\n", strconv.Quote(e.Child), strconv.Quote(e.Parent), strconv.Quote(e.Source))
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+func (g *Graph) sortedEdges() []Edge {
+	edges := make([]Edge, len(g.Edges))
+	copy(edges, g.Edges)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Child != edges[j].Child {
+			return edges[i].Child < edges[j].Child
+		}
+		return edges[i].Parent < edges[j].Parent
+	})
+	return edges
+}
+
+type graphJSON struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []Edge  `json:"edges"`
+}
+
+// MarshalJSON encodes graph as sorted lists of nodes and edges
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	out := graphJSON{Nodes: make([]*Node, 0, len(g.Nodes)), Edges: g.sortedEdges()}
+	for _, id := range g.sortedIDs() {
+		out.Nodes = append(out.Nodes, g.Nodes[id])
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes graph encoded by MarshalJSON
+func (g *Graph) UnmarshalJSON(b []byte) error {
+	var in graphJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*g = *New()
+	for _, n := range in.Nodes {
+		g.Nodes[n.ID] = n
+	}
+	for _, e := range in.Edges {
+		g.AddEdge(e.Child, e.Parent, e.Source)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+// Package topology builds dependency graph of Icinga2 hosts and services and finds root causes of problems
+package topology
+
+import (
+	"fmt"
+	"github.com/efigence/go-icinga2"
+	"sort"
+	"strings"
+)
+
+// ParentVar is the custom variable with name (or list of names) of the host's parent
+const ParentVar = "parent_host"
+
+// Source provides objects the graph is built from, *icinga2.API implements it
+type Source interface {
+	GetHostsDetailed(filter string) ([]icinga2.Host, error)
+	GetServicesDetailed(filter string) ([]icinga2.Service, error)
+	GetDependencies() ([]icinga2.Dependency, error)
+}
+
+// Node is a host or a service. ID is the host name or "host!service"
+type Node struct {
+	ID      string `json:"id"`
+	Host    string `json:"host"`
+	Service string `json:"service,omitempty"`
+	// Icinga2 state, 0 is UP/OK
+	State   float64 `json:"state"`
+	Problem bool    `json:"problem"`
+	// false if node is only referenced by dependency but was not returned by API
+	Known bool `json:"known"`
+}
+
+// Edge points from child to the parent it depends on
+type Edge struct {
+	Child  string `json:"child"`
+	Parent string `json:"parent"`
+	// dependency object name or "vars.parent_host"
+	Source string `json:"source"`
+}
+
+// Graph is dependency graph of hosts and services
+type Graph struct {
+	Nodes    map[string]*Node
+	Edges    []Edge
+	parents  map[string][]string
+	children map[string][]string
+}
+
+// NodeID returns id of host (empty service) or service node
+func NodeID(host string, service string) string {
+	if service == "" {
+		return host
+	}
+	return host + "!" + service
+}
+
+// New returns empty graph
+func New() *Graph {
+	return &Graph{
+		Nodes:    make(map[string]*Node),
+		parents:  make(map[string][]string),
+		children: make(map[string][]string),
+	}
+}
+
+// Load builds graph from Dependency objects and parent_host custom variables.
+// Services are only loaded if some dependency refers to them
+func Load(src Source) (*Graph, error) {
+	g := New()
+	hosts, err := src.GetHostsDetailed("")
+	if err != nil {
+		return nil, fmt.Errorf("error loading hosts: %s", err)
+	}
+	deps, err := src.GetDependencies()
+	if err != nil {
+		return nil, fmt.Errorf("error loading dependencies: %s", err)
+	}
+	for _, h := range hosts {
+		g.AddHost(h)
+	}
+	needServices := false
+	for _, d := range deps {
+		if d.ParentServiceName != "" || d.ChildServiceName != "" {
+			needServices = true
+			break
+		}
+	}
+	if needServices {
+		services, err := src.GetServicesDetailed("")
+		if err != nil {
+			return nil, fmt.Errorf("error loading services: %s", err)
+		}
+		for _, s := range services {
+			g.AddService(s)
+		}
+	}
+	for _, h := range hosts {
+		parents, _ := h.Vars.StringList(ParentVar)
+		for _, p := range parents {
+			if p != "" {
+				g.AddEdge(h.Name, p, "vars."+ParentVar)
+			}
+		}
+	}
+	for _, d := range deps {
+		g.AddEdge(NodeID(d.ChildHostName, d.ChildServiceName), NodeID(d.ParentHostName, d.ParentServiceName), d.Name)
+	}
+	return g, nil
+}
+
+// AddHost adds host node
+func (g *Graph) AddHost(h icinga2.Host) {
+	g.Nodes[h.Name] = &Node{
+		ID:      h.Name,
+		Host:    h.Name,
+		State:   h.State,
+		Problem: h.State != icinga2.IcingaHostUp,
+		Known:   true,
+	}
+}
+
+// AddService adds service node
+func (g *Graph) AddService(s icinga2.Service) {
+	id := NodeID(s.HostName, s.Name)
+	g.Nodes[id] = &Node{
+		ID:      id,
+		Host:    s.HostName,
+		Service: s.Name,
+		State:   s.State,
+		Problem: s.State != icinga2.IcingaServiceOK,
+		Known:   true,
+	}
+}
+
+// AddEdge adds dependency of child on parent, creating placeholder nodes for unknown objects
+func (g *Graph) AddEdge(child string, parent string, source string) {
+	for _, id := range []string{child, parent} {
+		if _, ok := g.Nodes[id]; !ok {
+			host, service, _ := strings.Cut(id, "!")
+			g.Nodes[id] = &Node{ID: id, Host: host, Service: service}
+		}
+	}
+	for _, p := range g.parents[child] {
+		if p == parent {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, Edge{Child: child, Parent: parent, Source: source})
+	g.parents[child] = append(g.parents[child], parent)
+	g.children[parent] = append(g.children[parent], child)
+}
+
+// Parents returns ids of nodes that node depends on
+func (g *Graph) Parents(id string) []string {
+	return g.parents[id]
+}
+
+// Children returns ids of nodes depending on node
+func (g *Graph) Children(id string) []string {
+	return g.children[id]
+}
+
+// Cycles returns dependency cycles, each as a sorted list of node ids
+func (g *Graph) Cycles() [][]string {
+	cycles := make([][]string, 0)
+	for _, c := range g.components() {
+		if len(c) > 1 || g.dependsOnItself(c[0]) {
+			cycles = append(cycles, c)
+		}
+	}
+	return cycles
+}
+
+// components returns strongly connected components (Tarjan's algorithm), each sorted
+func (g *Graph) components() [][]string {
+	index := 0
+	indexes := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	components := make([][]string, 0)
+	var connect func(id string)
+	connect = func(id string) {
+		indexes[id] = index
+		lowlink[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, p := range g.parents[id] {
+			if _, visited := indexes[p]; !visited {
+				connect(p)
+				if lowlink[p] < lowlink[id] {
+					lowlink[id] = lowlink[p]
+				}
+			} else if onStack[p] && indexes[p] < lowlink[id] {
+				lowlink[id] = indexes[p]
+			}
+		}
+		if lowlink[id] != indexes[id] {
+			return
+		}
+		component := make([]string, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	for _, id := range g.sortedIDs() {
+		if _, visited := indexes[id]; !visited {
+			connect(id)
+		}
+	}
+	return components
+}
+
+func (g *Graph) dependsOnItself(id string) bool {
+	for _, p := range g.parents[id] {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Graph) sortedIDs() []string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CauseOf returns root causes of node's problem: problem ancestors that have no parents in problem state.
+// Node that is in problem state only because of itself is its own root cause, failing cycle is the cause as a whole.
+// Returns nil for nodes without problem
+func (g *Graph) CauseOf(id string) []string {
+	return g.newCauses().causeOf(id)
+}
+
+// causes finds root causes on strongly connected components computed once, remembering result for each component
+type causes struct {
+	g           *Graph
+	components  [][]string
+	componentOf map[string]int
+	found       map[int]map[string]bool
+}
+
+func (g *Graph) newCauses() *causes {
+	c := &causes{
+		g:           g,
+		components:  g.components(),
+		componentOf: make(map[string]int),
+		found:       make(map[int]map[string]bool),
+	}
+	for i, component := range c.components {
+		for _, member := range component {
+			c.componentOf[member] = i
+		}
+	}
+	return c
+}
+
+func (c *causes) causeOf(id string) []string {
+	n, ok := c.g.Nodes[id]
+	if !ok || !n.Problem {
+		return nil
+	}
+	found := c.ofComponent(c.componentOf[id])
+	out := make([]string, 0, len(found))
+	for cause := range found {
+		out = append(out, cause)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ofComponent returns root causes of component. Components form acyclic graph so recursion always ends
+func (c *causes) ofComponent(i int) map[string]bool {
+	if found, ok := c.found[i]; ok {
+		return found
+	}
+	found := make(map[string]bool)
+	failingParents := 0
+	for _, member := range c.components[i] {
+		for _, p := range c.g.parents[member] {
+			if c.g.Nodes[p].Problem && c.componentOf[p] != i {
+				failingParents++
+				for cause := range c.ofComponent(c.componentOf[p]) {
+					found[cause] = true
+				}
+			}
+		}
+	}
+	if failingParents == 0 {
+		for _, member := range c.components[i] {
+			if c.g.Nodes[member].Problem {
+				found[member] = true
+			}
+		}
+	}
+	c.found[i] = found
+	return found
+}
+
+// RootCause is a problem that other problems depend on
+type RootCause struct {
+	ID string `json:"id"`
+	// problem nodes failing because of this one
+	Affected []string `json:"affected"`
+}
+
+// RootCauses returns all problem nodes that are not caused by other problems, with list of problems they cause
+func (g *Graph) RootCauses() []RootCause {
+	affected := make(map[string][]string)
+	c := g.newCauses()
+	for _, id := range g.sortedIDs() {
+		for _, cause := range c.causeOf(id) {
+			if cause != id {
+				affected[cause] = append(affected[cause], id)
+			} else if _, ok := affected[cause]; !ok {
+				affected[cause] = []string{}
+			}
+		}
+	}
+	out := make([]RootCause, 0, len(affected))
+	for id, a := range affected {
+		out = append(out, RootCause{ID: id, Affected: a})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
@@ -0,0 +1,138 @@
+package topology
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/efigence/go-icinga2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeSource struct {
+	hosts    []icinga2.Host
+	services []icinga2.Service
+	deps     []icinga2.Dependency
+}
+
+func (f *fakeSource) GetHostsDetailed(filter string) ([]icinga2.Host, error) {
+	return f.hosts, nil
+}
+
+func (f *fakeSource) GetServicesDetailed(filter string) ([]icinga2.Service, error) {
+	return f.services, nil
+}
+
+func (f *fakeSource) GetDependencies() ([]icinga2.Dependency, error) {
+	return f.deps, nil
+}
+
+func host(name string, state float64, parents ...interface{}) icinga2.Host {
+	var h icinga2.Host
+	h.Name = name
+	h.State = state
+	if len(parents) == 1 {
+		h.Vars = icinga2.Vars{ParentVar: parents[0]}
+	} else if len(parents) > 1 {
+		h.Vars = icinga2.Vars{ParentVar: parents}
+	}
+	return h
+}
+
+// core (down) <- sw1 (down) <- host1 (down), host2 (down)
+// core <- sw2 (up) <- host3 (down)
+// host4 (down, no parents)
+func testSource() *fakeSource {
+	var svc icinga2.Service
+	svc.HostName = "host3"
+	svc.Name = "HTTP"
+	svc.State = 2
+	return &fakeSource{
+		hosts: []icinga2.Host{
+			host("core", 1),
+			host("sw1", 1, "core"),
+			host("sw2", 0, "core"),
+			host("host1", 1, "sw1"),
+			host("host2", 1),
+			host("host3", 1, "sw2"),
+			host("host4", 1),
+		},
+		services: []icinga2.Service{svc},
+		deps: []icinga2.Dependency{
+			{Name: "host2-uplink", ChildHostName: "host2", ParentHostName: "sw1"},
+			{Name: "http-on-host", ChildHostName: "host3", ChildServiceName: "HTTP", ParentHostName: "host3"},
+		},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	g, err := Load(testSource())
+	require.Nil(t, err)
+	assert.Len(t, g.Nodes, 8)
+	assert.Equal(t, []string{"core"}, g.Parents("sw1"))
+	assert.ElementsMatch(t, []string{"host1", "host2"}, g.Children("sw1"))
+	assert.Equal(t, []string{"host3"}, g.Parents("host3!HTTP"))
+	assert.True(t, g.Nodes["host3!HTTP"].Problem)
+	assert.Empty(t, g.Cycles())
+}
+
+func TestGraph_RootCauses(t *testing.T) {
+	g, err := Load(testSource())
+	require.Nil(t, err)
+	assert.Equal(t, []string{"core"}, g.CauseOf("host1"))
+	assert.Equal(t, []string{"core"}, g.CauseOf("host2"))
+	assert.Equal(t, []string{"host3"}, g.CauseOf("host3"), "parent sw2 is up")
+	assert.Equal(t, []string{"host3"}, g.CauseOf("host3!HTTP"))
+	assert.Nil(t, g.CauseOf("sw2"), "no problem")
+	assert.Equal(t, []RootCause{
+		{ID: "core", Affected: []string{"host1", "host2", "sw1"}},
+		{ID: "host3", Affected: []string{"host3!HTTP"}},
+		{ID: "host4", Affected: []string{}},
+	}, g.RootCauses())
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	g := New()
+	g.AddHost(host("a", 1))
+	g.AddHost(host("b", 1))
+	g.AddHost(host("c", 1))
+	g.AddHost(host("e", 1))
+	g.AddEdge("a", "b", "test")
+	g.AddEdge("b", "c", "test")
+	g.AddEdge("c", "a", "test")
+	g.AddEdge("d", "d", "test")
+	g.AddEdge("e", "a", "test")
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d"}}, g.Cycles())
+	// must not loop forever
+	assert.Equal(t, []string{"a", "b", "c"}, g.CauseOf("e"))
+}
+
+func TestGraph_AddEdgePlaceholder(t *testing.T) {
+	g := New()
+	g.AddEdge("host1!HTTP", "host2", "test")
+	assert.Equal(t, &Node{ID: "host1!HTTP", Host: "host1", Service: "HTTP"}, g.Nodes["host1!HTTP"])
+	assert.Equal(t, &Node{ID: "host2", Host: "host2"}, g.Nodes["host2"])
+}
+
+func TestGraph_Export(t *testing.T) {
+	g, err := Load(testSource())
+	require.Nil(t, err)
+	var dot bytes.Buffer
+	require.Nil(t, g.WriteDOT(&dot))
+	assert.Contains(t, dot.String(), "digraph topology {")
+	assert.Contains(t, dot.String(), `"sw1" -> "core" [label="vars.parent_host"];`)
+	assert.Contains(t, dot.String(), `"host2" -> "sw1" [label="host2-uplink"];`)
+	assert.Contains(t, dot.String(), `"host3!HTTP" [shape=ellipse,color=red];`)
+
+	data, err := json.Marshal(g)
+	require.Nil(t, err)
+	var g2 Graph
+	require.Nil(t, json.Unmarshal(data, &g2))
+	assert.Equal(t, g.RootCauses(), g2.RootCauses())
+	assert.Len(t, g2.Edges, len(g.Edges))
+}
+
+func TestLoad_API(t *testing.T) {
+	// *icinga2.API has to satisfy Source
+	var _ Source = &icinga2.API{}
+}
@@ -0,0 +1,265 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FieldAttributes are flags of type field
+type FieldAttributes struct {
+	Config       bool `json:"config"`
+	Navigation   bool `json:"navigation"`
+	NoUserModify bool `json:"no_user_modify"`
+	NoUserView   bool `json:"no_user_view"`
+	Required     bool `json:"required"`
+	State        bool `json:"state"`
+}
+
+// TypeField describes attribute of Icinga2 type
+type TypeField struct {
+	ID   float64 `json:"id"`
+	Type string  `json:"type"`
+	// 1 for arrays, 0 for scalars
+	ArrayRank float64 `json:"array_rank"`
+	// name used to navigate to referenced object, e.g. "host" for "host_name"
+	NavigationName string          `json:"navigation_name"`
+	RefType        string          `json:"ref_type"`
+	Attributes     FieldAttributes `json:"attributes"`
+}
+
+// TypeDescription describes Icinga2 object type
+type TypeDescription struct {
+	Name       string `json:"name"`
+	PluralName string `json:"plural_name"`
+	// name of the parent type, empty for Object
+	Base          string               `json:"base"`
+	Abstract      bool                 `json:"abstract"`
+	Fields        map[string]TypeField `json:"fields"`
+	PrototypeKeys []string             `json:"prototype_keys"`
+}
+
+type typesResponse struct {
+	Results []TypeDescription `json:"results"`
+	Error   float64           `json:"error"`
+	Status  string            `json:"status"`
+}
+
+// GetTypes returns descriptions of all types
+func (a *API) GetTypes() (m []TypeDescription, err error) {
+	return a.getTypes("/v1/types")
+}
+
+// GetType returns description of single type
+func (a *API) GetType(name string) (t TypeDescription, err error) {
+	m, err := a.getTypes("/v1/types/" + url.PathEscape(name))
+	if err != nil {
+		return t, err
+	}
+	if len(m) == 0 {
+		return t, fmt.Errorf("type %s not found", name)
+	}
+	return m[0], nil
+}
+
+func (a *API) getTypes(path string) (m []TypeDescription, err error) {
+	body, err := a.request("GET", path, nil)
+	if err != nil {
+		return m, err
+	}
+	var resp typesResponse
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return m, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+	}
+	if resp.Error != 0 {
+		return m, fmt.Errorf("error getting types: [%d] %s", int(resp.Error), resp.Status)
+	}
+	return resp.Results, nil
+}
+
+// GetSchema returns schema of all types
+func (a *API) GetSchema() (*Schema, error) {
+	types, err := a.GetTypes()
+	if err != nil {
+		return nil, err
+	}
+	return NewSchema(types), nil
+}
+
+// Schema validates attribute names against type descriptions
+type Schema struct {
+	// lowercase type name to type
+	types map[string]TypeDescription
+}
+
+// NewSchema builds schema from type descriptions
+func NewSchema(types []TypeDescription) *Schema {
+	s := &Schema{types: make(map[string]TypeDescription, len(types))}
+	for _, t := range types {
+		s.types[strings.ToLower(t.Name)] = t
+	}
+	return s
+}
+
+// Type returns type description by case-insensitive name
+func (s *Schema) Type(name string) (t TypeDescription, ok bool) {
+	t, ok = s.types[strings.ToLower(name)]
+	return t, ok
+}
+
+// Field returns field of the type or any of its base types
+func (s *Schema) Field(typeName string, field string) (f TypeField, ok bool) {
+	for i := 0; i < len(s.types); i++ {
+		t, found := s.Type(typeName)
+		if !found {
+			return f, false
+		}
+		if f, ok = t.Fields[field]; ok {
+			return f, true
+		}
+		if t.Base == "" {
+			return f, false
+		}
+		typeName = t.Base
+	}
+	return f, false
+}
+
+// HasAttribute checks whether dotted attribute path exists in the type.
+// Dictionary fields (like vars) accept any subpath, fields of known types are checked recursively
+func (s *Schema) HasAttribute(typeName string, path string) bool {
+	parts := splitAttribute(path)
+	f, ok := s.Field(typeName, parts[0])
+	if !ok {
+		// joined object, e.g. host.name in service filter
+		if len(parts) == 2 {
+			if _, isType := s.Type(parts[0]); isType && s.navigable(typeName, parts[0]) {
+				return s.HasAttribute(parts[0], parts[1])
+			}
+		}
+		return false
+	}
+	if len(parts) == 1 {
+		return true
+	}
+	if f.Type == "Dictionary" || f.Type == "Value" {
+		return true
+	}
+	// index access like vars["x"] is only valid for dictionaries
+	if strings.HasPrefix(parts[1], "[") {
+		return false
+	}
+	if _, isType := s.Type(f.Type); isType {
+		return s.HasAttribute(f.Type, parts[1])
+	}
+	return false
+}
+
+// splitAttribute splits attribute path into the first field and the rest, which starts with "[" for index access
+func splitAttribute(path string) []string {
+	i := strings.IndexAny(path, ".[")
+	switch {
+	case i < 0:
+		return []string{path}
+	case path[i] == '[':
+		return []string{path[:i], path[i:]}
+	default:
+		return []string{path[:i], path[i+1:]}
+	}
+}
+
+// navigable checks whether type has field navigating to given name
+func (s *Schema) navigable(typeName string, navigation string) bool {
+	t, ok := s.Type(typeName)
+	for ok {
+		for _, f := range t.Fields {
+			if f.NavigationName == navigation {
+				return true
+			}
+		}
+		t, ok = s.Type(t.Base)
+	}
+	return false
+}
+
+// ValidateAttributes checks "type.attribute" names as used in filters, e.g. "host.vars.os" or "service.state"
+func (s *Schema) ValidateAttributes(attrs []string) error {
+	unknown := make([]string, 0)
+	for _, attr := range attrs {
+		parts := strings.SplitN(attr, ".", 2)
+		if len(parts) != 2 {
+			unknown = append(unknown, attr)
+			continue
+		}
+		if _, ok := s.Type(parts[0]); !ok || !s.HasAttribute(parts[0], parts[1]) {
+			unknown = append(unknown, attr)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown attributes: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// ValidateModify checks that attributes exist in the type and can be modified by user
+func (s *Schema) ValidateModify(typeName string, attrs map[string]interface{}) error {
+	if _, ok := s.Type(typeName); !ok {
+		return fmt.Errorf("unknown type %s", typeName)
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, ok := s.Field(typeName, splitAttribute(name)[0])
+		if !ok || !s.HasAttribute(typeName, name) {
+			return fmt.Errorf("unknown attribute %s of type %s", name, typeName)
+		}
+		if f.Attributes.NoUserModify {
+			return fmt.Errorf("attribute %s of type %s can't be modified", name, typeName)
+		}
+	}
+	return nil
+}
+
+// Validate checks that all attributes used in filter exist in schema
+func (f Filter) Validate(s *Schema) error {
+	return s.ValidateAttributes(f.Attributes())
+}
+
+// SetSchema enables local validation of attributes in ModifyObject, nil disables it
+func (a *API) SetSchema(s *Schema) {
+	a.schema = s
+}
+
+// ModifyObject changes attributes of object, e.g. ModifyObject("Host", "h1", map[string]interface{}{"vars.os": "Linux"}).
+// If schema is set, unknown and not modifiable attributes are rejected before sending request
+func (a *API) ModifyObject(objType string, name string, attrs map[string]interface{}) error {
+	plural := pluralize(objType)
+	if a.schema != nil {
+		err := a.schema.ValidateModify(objType, attrs)
+		if err != nil {
+			return err
+		}
+		t, _ := a.schema.Type(objType)
+		plural = t.PluralName
+	}
+	body, err := a.request("POST", "/v1/objects/"+url.PathEscape(plural)+"/"+url.PathEscape(name), map[string]interface{}{"attrs": attrs})
+	if err != nil {
+		return err
+	}
+	return statusError(body, "error modifying "+objType+" "+name)
+}
+
+// pluralize returns plural name of type as used in API paths
+func pluralize(objType string) string {
+	if strings.HasSuffix(objType, "y") {
+		return strings.TrimSuffix(objType, "y") + "ies"
+	}
+	return objType + "s"
+}
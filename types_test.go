@@ -0,0 +1,109 @@
+package icinga2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func testSchema(t *testing.T) *Schema {
+	ts := testServer(t, "/v1/types", "v1.types.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	s, err := Api.GetSchema()
+	require.Nil(t, err)
+	return s
+}
+
+func TestAPI_GetTypes(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/types", "v1.types.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	types, err := Api.GetTypes()
+	require.Nil(t, err)
+	require.Len(t, types, 6)
+	assert.Equal(t, "ConfigObject", types[1].Name)
+	assert.True(t, types[1].Abstract)
+	assert.Equal(t, "zone", types[1].Fields["zone"].NavigationName)
+	assert.True(t, types[1].Fields["name"].Attributes.NoUserModify)
+	assert.Equal(t, []string{"modify_attribute", "restore_attribute"}, types[1].PrototypeKeys)
+}
+
+func TestAPI_GetType(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/types/Host", "v1.types.host.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	host, err := Api.GetType("Host")
+	require.Nil(t, err)
+	assert.Equal(t, "Checkable", host.Base)
+	assert.Equal(t, "Hosts", host.PluralName)
+	assert.Equal(t, "String", host.Fields["address"].Type)
+}
+
+func TestSchema_ValidateAttributes(t *testing.T) {
+	log = testLogger{}
+	s := testSchema(t)
+	tests := []struct {
+		attr  string
+		valid bool
+	}{
+		{"host.name", true},
+		{"host.address", true},
+		{"Host.state", true},
+		{"host.vars.os", true},
+		{`host.vars["mgmt-ip"]`, true},
+		{`host.vars["mgmt-ip"].port`, true},
+		{`host.address["x"]`, false},
+		{"host.last_check_result.output", true},
+		{"host.last_check_result.nope", false},
+		{"host.adress", false},
+		{"service.host_name", true},
+		{"service.host.address", true},
+		{"host.service.name", false},
+		{"nosuchtype.name", false},
+		{"name", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.attr, func(t *testing.T) {
+			err := s.ValidateAttributes([]string{tt.attr})
+			if tt.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+	f := And(Eq("host.adress", "10.0.0.1"), Match("web*", "host.name"), Eq("host.foo", 1))
+	assert.EqualError(t, f.Validate(s), "unknown attributes: host.adress, host.foo")
+	assert.Nil(t, VarEq("host", "os", "Linux").Validate(s))
+	assert.Nil(t, VarHas("service", "mgmt-ip", "10.0.0.1").Validate(s))
+}
+
+func TestAPI_ModifyObject(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/objects/Hosts/t1-host1", "v1.objects.modify.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	require.Nil(t, Api.ModifyObject("Host", "t1-host1", map[string]interface{}{"vars.os": "Linux"}))
+	assert.JSONEq(t, `{"attrs":{"vars.os":"Linux"}}`, ts.LastBody())
+
+	Api.SetSchema(testSchema(t))
+	ts.Reset()
+	err = Api.ModifyObject("Host", "t1-host1", map[string]interface{}{"adress": "10.0.0.1"})
+	assert.EqualError(t, err, "unknown attribute adress of type Host")
+	err = Api.ModifyObject("Host", "t1-host1", map[string]interface{}{"groups": []string{"x"}})
+	assert.EqualError(t, err, "attribute groups of type Host can't be modified")
+	assert.Empty(t, ts.LastBody(), "invalid request should not be sent")
+	assert.Nil(t, Api.ModifyObject("Host", "t1-host1", map[string]interface{}{"check_interval": 60, "vars.os": "Linux"}))
+}
+
+func TestPluralize(t *testing.T) {
+	assert.Equal(t, "Hosts", pluralize("Host"))
+	assert.Equal(t, "Dependencies", pluralize("Dependency"))
+}
@@ -0,0 +1,167 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Variable is Icinga2 global variable or constant, e.g. NodeName, ZoneName or custom const
+type Variable struct {
+	Name string `json:"name"`
+	// Icinga2 type name, e.g. String, Number, Boolean, Array, Dictionary
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Decode decodes value into v
+func (v *Variable) Decode(out interface{}) error {
+	if len(v.Value) == 0 {
+		return json.Unmarshal([]byte("null"), out)
+	}
+	return json.Unmarshal(v.Value, out)
+}
+
+// AsString returns value if it is a string
+func (v *Variable) AsString() (s string, ok bool) {
+	ok = v.Decode(&s) == nil && v.Type == "String"
+	return s, ok
+}
+
+// AsNumber returns value if it is a number
+func (v *Variable) AsNumber() (n float64, ok bool) {
+	ok = v.Decode(&n) == nil && v.Type == "Number"
+	return n, ok
+}
+
+// AsBool returns value if it is a boolean
+func (v *Variable) AsBool() (b bool, ok bool) {
+	ok = v.Decode(&b) == nil && v.Type == "Boolean"
+	return b, ok
+}
+
+type variablesResponse struct {
+	Results []Variable `json:"results"`
+	Error   float64    `json:"error"`
+	Status  string     `json:"status"`
+}
+
+// GetVariables returns all global variables
+func (a *API) GetVariables() (m []Variable, err error) {
+	return a.getVariables("/v1/variables")
+}
+
+// GetVariable returns single global variable
+func (a *API) GetVariable(name string) (v Variable, err error) {
+	m, err := a.getVariables("/v1/variables/" + url.PathEscape(name))
+	if err != nil {
+		return v, err
+	}
+	if len(m) == 0 {
+		return v, fmt.Errorf("variable %s not found", name)
+	}
+	return m[0], nil
+}
+
+// NodeName returns name of the Icinga2 node API is connected to
+func (a *API) NodeName() (string, error) {
+	v, err := a.GetVariable("NodeName")
+	if err != nil {
+		return "", err
+	}
+	name, ok := v.AsString()
+	if !ok {
+		return "", fmt.Errorf("NodeName is not a string: %s", string(v.Value))
+	}
+	return name, nil
+}
+
+func (a *API) getVariables(path string) (m []Variable, err error) {
+	body, err := a.request("GET", path, nil)
+	if err != nil {
+		return m, err
+	}
+	var resp variablesResponse
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return m, fmt.Errorf("error decoding json: %s | %s", err, string(body))
+	}
+	if resp.Error != 0 {
+		return m, fmt.Errorf("error getting variables: [%d] %s", int(resp.Error), resp.Status)
+	}
+	return resp.Results, nil
+}
+
+// NodeNames returns NodeName of each server
+func (a *Proxy) NodeNames() (map[string]string, map[string]error) {
+	var lock sync.Mutex
+	names := make(map[string]string)
+	errs := a.each(func(name string, s *API) error {
+		node, err := s.NodeName()
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		names[name] = node
+		return nil
+	})
+	return names, errs
+}
+
+// DuplicateNodes returns servers pointing at the same Icinga2 node, keyed by NodeName.
+// Servers that failed to respond are skipped, error is returned only if all of them failed
+func (a *Proxy) DuplicateNodes() (map[string][]string, error) {
+	names, errs := a.NodeNames()
+	return duplicateNodes(names), proxyError(errs)
+}
+
+// CheckDuplicateNodes returns error if two servers point at the same Icinga2 node
+func (a *Proxy) CheckDuplicateNodes() error {
+	duplicates, err := a.DuplicateNodes()
+	if err != nil {
+		return err
+	}
+	return duplicateNodesError(duplicates)
+}
+
+// checkDuplicateNodes is run on (re)load. Servers that don't respond can't be checked so they are only logged
+func checkDuplicateNodes(servers map[string]*API) error {
+	names, errs := (&Proxy{servers: servers}).NodeNames()
+	for server, err := range errs {
+		if err != nil {
+			log.Printf("can't check node name of %s: %s", server, err)
+		}
+	}
+	return duplicateNodesError(duplicateNodes(names))
+}
+
+func duplicateNodes(names map[string]string) map[string][]string {
+	byNode := make(map[string][]string)
+	for server, node := range names {
+		byNode[node] = append(byNode[node], server)
+	}
+	duplicates := make(map[string][]string)
+	for node, servers := range byNode {
+		if len(servers) > 1 {
+			sort.Strings(servers)
+			duplicates[node] = servers
+		}
+	}
+	return duplicates
+}
+
+func duplicateNodesError(duplicates map[string][]string) error {
+	if len(duplicates) == 0 {
+		return nil
+	}
+	nodes := make([]string, 0, len(duplicates))
+	for node, servers := range duplicates {
+		nodes = append(nodes, fmt.Sprintf("%s: [%s]", node, strings.Join(servers, ", ")))
+	}
+	sort.Strings(nodes)
+	return fmt.Errorf("servers point at the same icinga2 node: %s", strings.Join(nodes, "; "))
+}
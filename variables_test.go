@@ -0,0 +1,114 @@
+package icinga2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI_GetVariables(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/variables", "v1.variables.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	vars, err := Api.GetVariables()
+	require.Nil(t, err)
+	require.Len(t, vars, 5)
+	zone, ok := vars[1].AsString()
+	assert.True(t, ok)
+	assert.Equal(t, "master", zone)
+	n, ok := vars[2].AsNumber()
+	assert.True(t, ok)
+	assert.Equal(t, 512.0, n)
+	_, ok = vars[2].AsString()
+	assert.False(t, ok)
+	b, ok := vars[3].AsBool()
+	assert.True(t, ok)
+	assert.True(t, b)
+	var dirs []string
+	require.Nil(t, vars[4].Decode(&dirs))
+	assert.Len(t, dirs, 2)
+}
+
+func TestAPI_GetVariable(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.json")
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	name, err := Api.NodeName()
+	require.Nil(t, err)
+	assert.Equal(t, "t1-mon1.example.com", name)
+
+	ts2 := testServer(t, "/v1/variables/Nope", "v1.variables.notfound.json")
+	defer ts2.Close()
+	Api, err = New(ts2.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	_, err = Api.GetVariable("Nope")
+	assert.EqualError(t, err, "error getting variables: [404] No such variable.")
+}
+
+func TestProxy_DuplicateNodes(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.json")
+	ts2 := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.json")
+	ts3 := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.2.json")
+	defer ts.Close()
+	defer ts2.Close()
+	defer ts3.Close()
+	p, err := NewProxy(map[string]Icinga2ServerConfig{
+		"s1":       {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s1-alias": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+		"s2":       {ServerURL: ts3.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	d, err := p.DuplicateNodes()
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]string{"t1-mon1.example.com": {"s1", "s1-alias"}}, d)
+	assert.EqualError(t, p.CheckDuplicateNodes(), "servers point at the same icinga2 node: t1-mon1.example.com: [s1, s1-alias]")
+
+	p, err = NewProxy(map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts3.URL, User: TestUser, Pass: TestPass},
+	})
+	require.Nil(t, err)
+	assert.Nil(t, p.CheckDuplicateNodes())
+}
+
+func TestProxy_RejectDuplicateNodes(t *testing.T) {
+	log = testLogger{}
+	ts := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.json")
+	ts2 := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.json")
+	ts3 := testServer(t, "/v1/variables/NodeName", "v1.variables.NodeName.2.json")
+	defer ts.Close()
+	defer ts2.Close()
+	defer ts3.Close()
+	cfg := &ProxyConfig{
+		Servers: map[string]Icinga2ServerConfig{
+			"s1":       {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+			"s1-alias": {ServerURL: ts2.URL, User: TestUser, Pass: TestPass},
+		},
+		RejectDuplicateNodes: true,
+	}
+	_, err := NewProxyFromConfig(cfg)
+	assert.EqualError(t, err, "servers point at the same icinga2 node: t1-mon1.example.com: [s1, s1-alias]")
+
+	cfg.Servers = map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts3.URL, User: TestUser, Pass: TestPass},
+	}
+	p, err := NewProxyFromConfig(cfg)
+	require.Nil(t, err)
+	cfg.Servers["s1-alias"] = Icinga2ServerConfig{ServerURL: ts2.URL, User: TestUser, Pass: TestPass}
+	assert.Error(t, p.Reload(cfg), "reload with duplicate should be rejected")
+	assert.Len(t, p.Servers(), 2, "old servers should be kept")
+
+	// unreachable server can't be checked and doesn't block loading
+	ts3.Close()
+	cfg.Servers = map[string]Icinga2ServerConfig{
+		"s1": {ServerURL: ts.URL, User: TestUser, Pass: TestPass},
+		"s2": {ServerURL: ts3.URL, User: TestUser, Pass: TestPass},
+	}
+	assert.Nil(t, p.Reload(cfg))
+}
@@ -0,0 +1,114 @@
+package icinga2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Vars are custom variables of an object. Nested values can be looked up by dotted path like "disks.root.path"
+type Vars map[string]interface{}
+
+// Get returns value at dotted path
+func (v Vars) Get(path string) (interface{}, bool) {
+	var cur interface{} = map[string]interface{}(v)
+	for _, part := range strings.Split(path, ".") {
+		m, ok := asMap(cur)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case Vars:
+		return m, true
+	}
+	return nil, false
+}
+
+// String returns string value at path. Numbers and bools are not converted
+func (v Vars) String(path string) (string, bool) {
+	val, ok := v.Get(path)
+	if !ok {
+		return "", false
+	}
+	s, ok := val.(string)
+	return s, ok
+}
+
+// Number returns numeric value at path
+func (v Vars) Number(path string) (float64, bool) {
+	val, ok := v.Get(path)
+	if !ok {
+		return 0, false
+	}
+	f, ok := val.(float64)
+	return f, ok
+}
+
+// Bool returns boolean value at path
+func (v Vars) Bool(path string) (bool, bool) {
+	val, ok := v.Get(path)
+	if !ok {
+		return false, false
+	}
+	b, ok := val.(bool)
+	return b, ok
+}
+
+// List returns array value at path
+func (v Vars) List(path string) ([]interface{}, bool) {
+	val, ok := v.Get(path)
+	if !ok {
+		return nil, false
+	}
+	l, ok := val.([]interface{})
+	return l, ok
+}
+
+// StringList returns array value at path as strings. Single string is returned as one element list
+func (v Vars) StringList(path string) ([]string, bool) {
+	val, ok := v.Get(path)
+	if !ok {
+		return nil, false
+	}
+	switch l := val.(type) {
+	case string:
+		return []string{l}, true
+	case []interface{}:
+		out := make([]string, len(l))
+		for i, item := range l {
+			out[i] = fmt.Sprint(item)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// Map returns dictionary value at path
+func (v Vars) Map(path string) (Vars, bool) {
+	val, ok := v.Get(path)
+	if !ok {
+		return nil, false
+	}
+	m, ok := asMap(val)
+	return m, ok
+}
+
+// GetHostsByVar returns hosts whose custom variable at path equals value or, if it is an array, contains it
+func (a *API) GetHostsByVar(path string, value interface{}) (m []Host, err error) {
+	return a.QueryHosts(QueryOptions{Filter: VarHas("host", path, value).String()})
+}
+
+// GetServicesByVar returns services whose custom variable at path equals value or, if it is an array, contains it
+func (a *API) GetServicesByVar(path string, value interface{}) (m []Service, err error) {
+	return a.QueryServices(QueryOptions{Filter: VarHas("service", path, value).String()})
+}
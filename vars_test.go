@@ -0,0 +1,81 @@
+package icinga2
+
+import (
+	"encoding/json"
+	"github.com/efigence/go-icinga2/internal/icingatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+func TestVars(t *testing.T) {
+	var v Vars
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"site": "dc1",
+		"internal_notification_groups": ["chat-admin", "mail"],
+		"sla": 99.9,
+		"nrpe_v2": true,
+		"disks": {"root": {"path": "/", "warn": "10%"}}
+	}`), &v))
+	s, ok := v.String("site")
+	assert.True(t, ok)
+	assert.Equal(t, "dc1", s)
+	_, ok = v.String("sla")
+	assert.False(t, ok, "no conversion")
+	n, ok := v.Number("sla")
+	assert.True(t, ok)
+	assert.Equal(t, 99.9, n)
+	b, ok := v.Bool("nrpe_v2")
+	assert.True(t, ok)
+	assert.True(t, b)
+	l, ok := v.StringList("internal_notification_groups")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"chat-admin", "mail"}, l)
+	l, ok = v.StringList("site")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"dc1"}, l)
+	raw, ok := v.List("internal_notification_groups")
+	assert.True(t, ok)
+	assert.Len(t, raw, 2)
+	s, ok = v.String("disks.root.path")
+	assert.True(t, ok)
+	assert.Equal(t, "/", s)
+	m, ok := v.Map("disks.root")
+	assert.True(t, ok)
+	assert.Equal(t, "10%", m["warn"])
+	_, ok = v.Get("disks.home.path")
+	assert.False(t, ok)
+	_, ok = v.Get("site.name")
+	assert.False(t, ok, "can't descend into string")
+	var empty Vars
+	_, ok = empty.Get("site")
+	assert.False(t, ok)
+}
+
+func TestHostVars(t *testing.T) {
+	log = testLogger{}
+	f, err := ioutil.ReadFile("testdata/v1.objects.host.json")
+	require.Nil(t, err)
+	var i Icinga2APIResponse
+	require.Nil(t, json.Unmarshal(f, &i))
+	hosts := i.GetHostsDetailed()
+	require.Len(t, hosts, 1)
+	groups, ok := hosts[0].Vars.StringList("internal_notification_groups")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"chat-admin"}, groups)
+	parent, _ := hosts[0].Vars.String("parent_host")
+	assert.Equal(t, "parent1", parent)
+}
+
+func TestAPI_GetHostsByVar(t *testing.T) {
+	log = testLogger{}
+	ts := icingatest.NewServer(t, "testdata", map[string]string{"/v1/objects/Hosts": "v1.objects.host.json"})
+	defer ts.Close()
+	Api, err := New(ts.URL, TestUser, TestPass)
+	require.Nil(t, err)
+	hosts, err := Api.GetHostsByVar("internal_notification_groups", "chat-admin")
+	require.Nil(t, err)
+	assert.Len(t, hosts, 1)
+	assert.Equal(t, `host.vars.internal_notification_groups=="chat-admin" || (typeof(host.vars.internal_notification_groups)==Array && "chat-admin" in host.vars.internal_notification_groups)`, ts.LastQuery().Get("filter"))
+}
@@ -0,0 +1,205 @@
+package icinga2
+
+import (
+	"fmt"
+	"github.com/efigence/go-monitoring"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StateSource provides snapshots of hosts and services, implemented by API, Proxy and CachedProxy
+type StateSource interface {
+	GetHosts() ([]monitoring.Host, error)
+	GetServices() ([]monitoring.Service, error)
+}
+
+// EventType is kind of change detected by Watcher
+type EventType string
+
+const (
+	EventAdded           EventType = "added"
+	EventRemoved         EventType = "removed"
+	EventStateChanged    EventType = "state_changed"
+	EventAckChanged      EventType = "ack_changed"
+	EventDowntimeChanged EventType = "downtime_changed"
+)
+
+// Event is change of host or service between two consecutive snapshots
+type Event struct {
+	Type EventType `json:"type"`
+	Host string    `json:"host"`
+	// empty for host events
+	Service string `json:"service,omitempty"`
+	// Old is nil for added objects, New is nil for removed ones
+	Old  *monitoring.Common `json:"old,omitempty"`
+	New  *monitoring.Common `json:"new,omitempty"`
+	Time time.Time          `json:"time"`
+}
+
+// IsHost returns true for host events
+func (e *Event) IsHost() bool {
+	return e.Service == ""
+}
+
+type watchedObject struct {
+	host    string
+	service string
+	common  monitoring.Common
+}
+
+// Watcher polls StateSource and emits events for changes between consecutive snapshots.
+// If the source fails, snapshot is kept and compared with the next successful one.
+// Proxy returns partial results if some of its servers fail, which looks like removal of their objects;
+// use CachedProxy as the source to avoid that
+type Watcher struct {
+	src      StateSource
+	interval time.Duration
+	events   chan Event
+	lock     sync.Mutex
+	objects  map[string]watchedObject
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewWatcher takes initial snapshot synchronously (no events are emitted for it) then polls source every interval
+func NewWatcher(src StateSource, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("watcher interval has to be positive, not %s", interval)
+	}
+	w := &Watcher{
+		src:      src,
+		interval: interval,
+		events:   make(chan Event, 64),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	objects, err := w.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	w.objects = objects
+	go w.run()
+	return w, nil
+}
+
+// Events returns channel of detected changes, it is closed after Close
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Close stops polling and closes events channel
+func (w *Watcher) Close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	defer close(w.events)
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-t.C:
+		}
+		events, err := w.Poll()
+		if err != nil {
+			log.Printf("error polling state: %s", err)
+			continue
+		}
+		for _, e := range events {
+			select {
+			case w.events <- e:
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}
+
+// Poll takes snapshot immediately and returns changes since the previous one.
+// Events returned by Poll are not sent to Events channel
+func (w *Watcher) Poll() ([]Event, error) {
+	// snapshot is taken under the lock too, otherwise concurrent poll could replace it with older one
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	objects, err := w.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	events := diffObjects(w.objects, objects, time.Now())
+	w.objects = objects
+	return events, nil
+}
+
+func (w *Watcher) snapshot() (map[string]watchedObject, error) {
+	hosts, err := w.src.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+	services, err := w.src.GetServices()
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]watchedObject, len(hosts)+len(services))
+	for _, h := range hosts {
+		objects[h.Host] = watchedObject{host: h.Host, common: h.Common}
+	}
+	for _, s := range services {
+		objects[s.Host+"!"+s.Service] = watchedObject{host: s.Host, service: s.Service, common: s.Common}
+	}
+	return objects, nil
+}
+
+// diffObjects returns events ordered by object name
+func diffObjects(old map[string]watchedObject, current map[string]watchedObject, now time.Time) []Event {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	events := make([]Event, 0)
+	for _, name := range names {
+		o, wasPresent := old[name]
+		n, isPresent := current[name]
+		event := func(t EventType) Event {
+			e := Event{Type: t, Host: n.host, Service: n.service, Time: now}
+			if wasPresent {
+				e.Host, e.Service = o.host, o.service
+				oldCommon := o.common
+				e.Old = &oldCommon
+			}
+			if isPresent {
+				newCommon := n.common
+				e.New = &newCommon
+			}
+			return e
+		}
+		switch {
+		case !wasPresent:
+			events = append(events, event(EventAdded))
+		case !isPresent:
+			events = append(events, event(EventRemoved))
+		default:
+			if o.common.State != n.common.State || o.common.StateHard != n.common.StateHard {
+				events = append(events, event(EventStateChanged))
+			}
+			if o.common.Acknowledged != n.common.Acknowledged {
+				events = append(events, event(EventAckChanged))
+			}
+			if o.common.Downtime != n.common.Downtime {
+				events = append(events, event(EventDowntimeChanged))
+			}
+		}
+	}
+	return events
+}
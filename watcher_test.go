@@ -0,0 +1,171 @@
+package icinga2
+
+import (
+	"fmt"
+	"github.com/efigence/go-monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeStateSource struct {
+	sync.Mutex
+	hosts    []monitoring.Host
+	services []monitoring.Service
+	err      error
+}
+
+func (f *fakeStateSource) GetHosts() ([]monitoring.Host, error) {
+	f.Lock()
+	defer f.Unlock()
+	return append([]monitoring.Host{}, f.hosts...), f.err
+}
+
+func (f *fakeStateSource) GetServices() ([]monitoring.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+	return append([]monitoring.Service{}, f.services...), f.err
+}
+
+func watchedService(host string, service string, state uint8) monitoring.Service {
+	var s monitoring.Service
+	s.Host = host
+	s.Service = service
+	s.State = state
+	s.StateHard = true
+	return s
+}
+
+func TestNewWatcher_Interval(t *testing.T) {
+	_, err := NewWatcher(&fakeStateSource{}, 0)
+	assert.Error(t, err)
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	log = testLogger{}
+	var h monitoring.Host
+	h.Host = "h1"
+	h.State = monitoring.HostUp
+	src := &fakeStateSource{
+		hosts: []monitoring.Host{h},
+		services: []monitoring.Service{
+			watchedService("h1", "HTTP", monitoring.StatusOk),
+			watchedService("h1", "SSH", monitoring.StatusOk),
+		},
+	}
+	w, err := NewWatcher(src, time.Hour)
+	require.Nil(t, err)
+	defer w.Close()
+
+	events, err := w.Poll()
+	require.Nil(t, err)
+	assert.Empty(t, events, "no changes")
+
+	src.Lock()
+	src.hosts[0].Downtime = true
+	src.services[0].State = monitoring.StatusCritical
+	src.services[0].Acknowledged = true
+	src.services[1] = watchedService("h1", "DISK", monitoring.StatusWarning)
+	src.Unlock()
+	events, err = w.Poll()
+	require.Nil(t, err)
+	require.Len(t, events, 5)
+	assert.Equal(t, EventDowntimeChanged, events[0].Type)
+	assert.True(t, events[0].IsHost())
+	assert.Equal(t, "h1", events[0].Host)
+	assert.Equal(t, EventAdded, events[1].Type)
+	assert.Equal(t, "DISK", events[1].Service)
+	assert.Nil(t, events[1].Old)
+	assert.Equal(t, EventStateChanged, events[2].Type)
+	assert.Equal(t, "HTTP", events[2].Service)
+	assert.Equal(t, uint8(monitoring.StatusOk), events[2].Old.State)
+	assert.Equal(t, uint8(monitoring.StatusCritical), events[2].New.State)
+	assert.Equal(t, EventAckChanged, events[3].Type)
+	assert.Equal(t, EventRemoved, events[4].Type)
+	assert.Equal(t, "SSH", events[4].Service)
+	assert.Nil(t, events[4].New)
+
+	src.Lock()
+	src.err = fmt.Errorf("connection refused")
+	src.Unlock()
+	_, err = w.Poll()
+	assert.NotNil(t, err)
+	src.Lock()
+	src.err = nil
+	src.Unlock()
+	events, err = w.Poll()
+	require.Nil(t, err)
+	assert.Empty(t, events, "snapshot kept on error")
+}
+
+// flappingSource changes state of the service on every snapshot
+type flappingSource struct {
+	sync.Mutex
+	calls int
+}
+
+func (f *flappingSource) GetHosts() ([]monitoring.Host, error) {
+	return nil, nil
+}
+
+func (f *flappingSource) GetServices() ([]monitoring.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls++
+	state := uint8(monitoring.StatusOk)
+	if f.calls%2 == 0 {
+		state = monitoring.StatusCritical
+	}
+	return []monitoring.Service{watchedService("h1", "HTTP", state)}, nil
+}
+
+func TestWatcher_PollConcurrent(t *testing.T) {
+	log = testLogger{}
+	w, err := NewWatcher(&flappingSource{}, time.Hour)
+	require.Nil(t, err)
+	defer w.Close()
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	count := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			events, err := w.Poll()
+			assert.Nil(t, err)
+			lock.Lock()
+			count += len(events)
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 20, count, "every poll should see the change since the previous one")
+}
+
+func TestWatcher_Events(t *testing.T) {
+	log = testLogger{}
+	src := &fakeStateSource{services: []monitoring.Service{watchedService("h1", "HTTP", monitoring.StatusOk)}}
+	w, err := NewWatcher(src, time.Millisecond*10)
+	require.Nil(t, err)
+	src.Lock()
+	src.services[0].State = monitoring.StatusWarning
+	src.Unlock()
+	select {
+	case e := <-w.Events():
+		assert.Equal(t, EventStateChanged, e.Type)
+		assert.Equal(t, "HTTP", e.Service)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event received")
+	}
+	w.Close()
+	for range w.Events() {
+	}
+}
+
+func TestWatcher_Proxy(t *testing.T) {
+	var _ StateSource = &API{}
+	var _ StateSource = &Proxy{}
+	var _ StateSource = &CachedProxy{}
+}